@@ -3,16 +3,22 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Redis    RedisConfig
-	Server   ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	Server    ServerConfig
+	RateLimit RateLimitConfig
+	Campus    CampusConfig
 }
 
+var AppConfig *Config
+
 type DatabaseConfig struct {
 	Host     string
 	Port     string
@@ -32,11 +38,25 @@ type ServerConfig struct {
 	Port string
 }
 
+// RateLimitConfig 消息发送限流配置，限制数为0表示不限制
+type RateLimitConfig struct {
+	Window    time.Duration // 滑动窗口长度
+	UserLimit int           // 每个用户在窗口内最多发送的消息数
+	RoomLimit int           // 每个聊天室在窗口内最多接收的消息数
+	IPLimit   int           // 每个IP在窗口内最多发送的消息数
+}
+
+// CampusConfig 校园相关配置
+type CampusConfig struct {
+	Timezone           string // 打卡日期和统计范围使用的默认时区（IANA名称），聊天室可单独设置
+	CheckInSummaryTime string // 每日打卡总结的发送时间（HH:MM，聊天室时区），为空表示不发送
+}
+
 func LoadConfig() *Config {
 	// 加载.env文件
 	godotenv.Load()
 
-	return &Config{
+	AppConfig = &Config{
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "100.65.111.80"),
 			Port:     getEnv("DB_PORT", "3307"),
@@ -53,7 +73,27 @@ func LoadConfig() *Config {
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8080"),
 		},
+		RateLimit: RateLimitConfig{
+			Window:    time.Duration(getEnvInt("RATE_LIMIT_WINDOW_SECONDS", 10)) * time.Second,
+			UserLimit: getEnvInt("RATE_LIMIT_USER", 20),
+			RoomLimit: getEnvInt("RATE_LIMIT_ROOM", 200),
+			IPLimit:   getEnvInt("RATE_LIMIT_IP", 60),
+		},
+		Campus: CampusConfig{
+			Timezone:           getEnv("CAMPUS_TIMEZONE", "Asia/Shanghai"),
+			CheckInSummaryTime: getEnv("CHECKIN_SUMMARY_TIME", "22:00"),
+		},
 	}
+
+	return AppConfig
+}
+
+// GetConfig 获取已加载的配置
+func GetConfig() *Config {
+	if AppConfig == nil {
+		return LoadConfig()
+	}
+	return AppConfig
 }
 
 func (c *Config) GetDSN() string {
@@ -71,4 +111,11 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"campus-canvas-chat/middleware"
+	"campus-canvas-chat/services"
+	"campus-canvas-chat/websocket"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminController struct {
+	adminService *services.AdminService
+	webSocketHub *websocket.Hub
+}
+
+func NewAdminController(webSocketHub *websocket.Hub) *AdminController {
+	return &AdminController{
+		adminService: services.NewAdminService(),
+		webSocketHub: webSocketHub,
+	}
+}
+
+// GetPendingChatRooms 获取待审核的聊天室列表
+func (ctrl *AdminController) GetPendingChatRooms(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	rooms, total, err := ctrl.adminService.GetPendingChatRooms(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"rooms":      rooms,
+			"total":      total,
+			"page":       page,
+			"page_size":  pageSize,
+			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// ReviewChatRoom 审核聊天室
+func (ctrl *AdminController) ReviewChatRoom(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	var req struct {
+		Approved bool   `json:"approved"`
+		Reason   string `json:"reason" binding:"max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 拒绝时必须填写原因
+	if !req.Approved && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "拒绝审核时必须填写原因"})
+		return
+	}
+
+	admin := middleware.GetAdmin(c)
+	if err := ctrl.adminService.ReviewChatRoom(admin.UserID, roomID, req.Approved, req.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "聊天室审核通过"
+	if !req.Approved {
+		message = "聊天室审核拒绝"
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// DeactivateChatRoom 强制停用聊天室
+func (ctrl *AdminController) DeactivateChatRoom(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required,max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin := middleware.GetAdmin(c)
+	if err := ctrl.adminService.DeactivateChatRoom(admin.UserID, roomID, req.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通知聊天室内的在线用户
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":   "room_deactivated",
+		"roomId": roomID,
+		"reason": req.Reason,
+	})
+	ctrl.webSocketHub.BroadcastToRoom(roomID, messageData)
+
+	c.JSON(http.StatusOK, gin.H{"message": "聊天室已停用"})
+}
+
+// SetUserStatus 启用或禁用用户
+func (ctrl *AdminController) SetUserStatus(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required,oneof=ACTIVE DISABLED"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.adminService.SetUserStatus(middleware.GetAdmin(c), userID, req.Status); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "用户已启用"
+	if req.Status == "DISABLED" {
+		message = "用户已禁用"
+		ctrl.webSocketHub.DisconnectUser(userID, "账号已被禁用")
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// GetAdmins 获取管理员列表
+func (ctrl *AdminController) GetAdmins(c *gin.Context) {
+	admins, err := ctrl.adminService.GetAdmins()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": admins})
+}
+
+// CreateAdmin 添加管理员
+func (ctrl *AdminController) CreateAdmin(c *gin.Context) {
+	var req struct {
+		UserID int64  `json:"userId" binding:"required"`
+		Role   string `json:"role" binding:"required,oneof=SUPER_ADMIN MODERATOR"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, err := ctrl.adminService.CreateAdmin(middleware.GetAdmin(c), req.UserID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "管理员添加成功",
+		"data":    admin,
+	})
+}
+
+// UpdateAdmin 修改管理员角色或启用状态
+func (ctrl *AdminController) UpdateAdmin(c *gin.Context) {
+	adminIDStr := c.Param("admin_id")
+	adminID, err := strconv.ParseInt(adminIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的管理员ID"})
+		return
+	}
+
+	var req struct {
+		Role     string `json:"role" binding:"omitempty,oneof=SUPER_ADMIN MODERATOR"`
+		IsActive *bool  `json:"isActive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Role != "" {
+		updates["role"] = req.Role
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的字段"})
+		return
+	}
+
+	updates["updated_at"] = time.Now()
+
+	if err := ctrl.adminService.UpdateAdmin(middleware.GetAdmin(c), adminID, updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "管理员信息更新成功"})
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"campus-canvas-chat/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditLogController struct {
+	auditLogService *services.AuditLogService
+}
+
+func NewAuditLogController() *AuditLogController {
+	return &AuditLogController{
+		auditLogService: services.NewAuditLogService(),
+	}
+}
+
+// GetRoomAuditLogs 获取聊天室审计日志（房主）
+func (ctrl *AuditLogController) GetRoomAuditLogs(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return
+	}
+
+	filter, ok := parseAuditLogFilter(c)
+	if !ok {
+		return
+	}
+	page, pageSize := parseAuditLogPage(c)
+
+	logs, total, err := ctrl.auditLogService.GetRoomAuditLogs(roomID, operatorID, filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondAuditLogs(c, logs, total, page, pageSize)
+}
+
+// GetAuditLogs 获取全平台审计日志（平台管理员）
+func (ctrl *AuditLogController) GetAuditLogs(c *gin.Context) {
+	filter, ok := parseAuditLogFilter(c)
+	if !ok {
+		return
+	}
+
+	// 可选的聊天室过滤
+	if roomIDStr := c.Query("chat_room_id"); roomIDStr != "" {
+		roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+			return
+		}
+		filter.ChatRoomID = &roomID
+	}
+
+	page, pageSize := parseAuditLogPage(c)
+
+	logs, total, err := ctrl.auditLogService.GetAuditLogs(filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondAuditLogs(c, logs, total, page, pageSize)
+}
+
+// parseAuditLogFilter 解析操作者、操作类型和日期范围过滤条件，解析失败时已写入错误响应
+func parseAuditLogFilter(c *gin.Context) (services.AuditLogFilter, bool) {
+	var filter services.AuditLogFilter
+
+	if actorIDStr := c.Query("actor_id"); actorIDStr != "" {
+		actorID, err := strconv.ParseInt(actorIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+			return filter, false
+		}
+		filter.ActorID = &actorID
+	}
+
+	filter.Action = c.Query("action")
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err := time.ParseInLocation("2006-01-02", startDateStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期格式"})
+			return filter, false
+		}
+		filter.StartTime = &startDate
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err := time.ParseInLocation("2006-01-02", endDateStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期格式"})
+			return filter, false
+		}
+		// 结束日期包含当天
+		endTime := endDate.AddDate(0, 0, 1)
+		filter.EndTime = &endTime
+	}
+
+	return filter, true
+}
+
+// parseAuditLogPage 解析分页参数
+func parseAuditLogPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	return page, pageSize
+}
+
+// respondAuditLogs 返回分页的审计日志
+func respondAuditLogs(c *gin.Context, logs interface{}, total int64, page, pageSize int) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"logs":       logs,
+			"total":      total,
+			"page":       page,
+			"page_size":  pageSize,
+			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
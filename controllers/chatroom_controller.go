@@ -3,6 +3,8 @@ package controllers
 import (
 	"campus-canvas-chat/models"
 	"campus-canvas-chat/services"
+	"campus-canvas-chat/websocket"
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,11 +14,13 @@ import (
 
 type ChatRoomController struct {
 	chatRoomService *services.ChatRoomService
+	webSocketHub    *websocket.Hub
 }
 
-func NewChatRoomController() *ChatRoomController {
+func NewChatRoomController(webSocketHub *websocket.Hub) *ChatRoomController {
 	return &ChatRoomController{
 		chatRoomService: services.NewChatRoomService(),
+		webSocketHub:    webSocketHub,
 	}
 }
 
@@ -28,6 +32,7 @@ func (ctrl *ChatRoomController) CreateChatRoom(c *gin.Context) {
 		Category    string `json:"category" binding:"required,min=1,max=50"`
 		MaxMembers  int    `json:"maxMembers" binding:"min=1,max=1000"`
 		CreatorID   int64  `json:"creatorId" binding:"required"`
+		JoinPolicy  string `json:"joinPolicy" binding:"omitempty,oneof=OPEN APPROVAL INVITE"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -43,10 +48,15 @@ func (ctrl *ChatRoomController) CreateChatRoom(c *gin.Context) {
 		MaxMembers:  req.MaxMembers,
 		IsActive:    true,
 		IsApproved:  false, // 需要审核
+		JoinPolicy:  "OPEN",
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
+	if req.JoinPolicy != "" {
+		chatRoom.JoinPolicy = req.JoinPolicy
+	}
+
 	if err := ctrl.chatRoomService.CreateChatRoom(chatRoom); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -117,7 +127,9 @@ func (ctrl *ChatRoomController) JoinChatRoom(c *gin.Context) {
 	}
 
 	var req struct {
-		UserID int64 `json:"userId" binding:"required"`
+		UserID     int64  `json:"userId" binding:"required"`
+		InviteCode string `json:"inviteCode"`
+		Message    string `json:"message" binding:"max=200"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -125,14 +137,43 @@ func (ctrl *ChatRoomController) JoinChatRoom(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.chatRoomService.JoinChatRoom(roomID, req.UserID); err != nil {
+	request, err := ctrl.chatRoomService.JoinChatRoom(roomID, req.UserID, req.InviteCode, req.Message)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 需要审批的聊天室，通知房主和管理员有新的入群申请
+	if request != nil {
+		ctrl.notifyJoinRequest(roomID, request)
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "入群申请已提交，等待审核",
+			"data":    request,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "成功加入聊天室"})
 }
 
+// notifyJoinRequest 通知房主和管理员有新的入群申请
+func (ctrl *ChatRoomController) notifyJoinRequest(roomID int64, request *models.ChatRoomJoinRequest) {
+	managerIDs, err := ctrl.chatRoomService.GetRoomManagerIDs(roomID)
+	if err != nil {
+		return
+	}
+
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":    "join_request",
+		"roomId":  roomID,
+		"request": request,
+	})
+	for _, managerID := range managerIDs {
+		ctrl.webSocketHub.SendToUser(managerID, messageData)
+	}
+}
+
 // LeaveChatRoom 离开聊天室
 func (ctrl *ChatRoomController) LeaveChatRoom(c *gin.Context) {
 	roomIDStr := c.Param("id")
@@ -203,8 +244,8 @@ func (ctrl *ChatRoomController) GetUserChatRooms(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": rooms})
 }
 
-// ApproveChatRoom 审核聊天室（管理员功能）
-func (ctrl *ChatRoomController) ApproveChatRoom(c *gin.Context) {
+// UpdateMemberRole 更新成员角色
+func (ctrl *ChatRoomController) UpdateMemberRole(c *gin.Context) {
 	roomIDStr := c.Param("id")
 	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
 	if err != nil {
@@ -213,7 +254,9 @@ func (ctrl *ChatRoomController) ApproveChatRoom(c *gin.Context) {
 	}
 
 	var req struct {
-		Approved bool `json:"approved"`
+		OperatorID   int64  `json:"operatorId" binding:"required"`
+		TargetUserID int64  `json:"targetUserId" binding:"required"`
+		NewRole      string `json:"newRole" binding:"required,oneof=MEMBER ADMIN"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -221,21 +264,173 @@ func (ctrl *ChatRoomController) ApproveChatRoom(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.chatRoomService.ApproveChatRoom(roomID, req.Approved); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := ctrl.chatRoomService.UpdateMemberRole(roomID, req.OperatorID, req.TargetUserID, req.NewRole); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
 
-	message := "聊天室审核通过"
-	if !req.Approved {
-		message = "聊天室审核拒绝"
+	c.JSON(http.StatusOK, gin.H{"message": "成员角色更新成功"})
+}
+
+// MuteMember 禁言成员
+func (ctrl *ChatRoomController) MuteMember(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID      int64  `json:"operatorId" binding:"required"`
+		TargetUserID    int64  `json:"targetUserId" binding:"required"`
+		Muted           bool   `json:"muted"`
+		DurationMinutes int    `json:"durationMinutes" binding:"min=0"` // 0表示永久禁言
+		Reason          string `json:"reason" binding:"max=200"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	member, err := ctrl.chatRoomService.MuteMember(roomID, req.OperatorID, req.TargetUserID, req.Muted, duration, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通知被操作的用户
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":       "mute_status",
+		"roomId":     roomID,
+		"muted":      member.IsMuted,
+		"mutedUntil": member.MutedUntil,
+		"reason":     member.MuteReason,
+	})
+	ctrl.webSocketHub.SendToUser(req.TargetUserID, messageData)
+
+	message := "成员禁言成功"
+	if !req.Muted {
+		message = "成员解除禁言成功"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    member,
+	})
+}
+
+// SetAllMuted 开启或关闭全员禁言
+func (ctrl *ChatRoomController) SetAllMuted(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64 `json:"operatorId" binding:"required"`
+		Enabled    bool  `json:"enabled"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.chatRoomService.SetAllMuted(roomID, req.OperatorID, req.Enabled); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通知聊天室内的在线用户
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":    "room_mute_all",
+		"roomId":  roomID,
+		"enabled": req.Enabled,
+	})
+	ctrl.webSocketHub.BroadcastToRoom(roomID, messageData)
+
+	message := "已开启全员禁言"
+	if !req.Enabled {
+		message = "已关闭全员禁言"
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": message})
 }
 
-// UpdateMemberRole 更新成员角色
-func (ctrl *ChatRoomController) UpdateMemberRole(c *gin.Context) {
+// SetSlowMode 设置慢速模式
+func (ctrl *ChatRoomController) SetSlowMode(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64 `json:"operatorId" binding:"required"`
+		Seconds    int   `json:"seconds" binding:"min=0,max=3600"` // 0表示关闭
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.chatRoomService.SetSlowMode(roomID, req.OperatorID, req.Seconds); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通知聊天室内的在线用户
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":    "room_slow_mode",
+		"roomId":  roomID,
+		"seconds": req.Seconds,
+	})
+	ctrl.webSocketHub.BroadcastToRoom(roomID, messageData)
+
+	message := "已开启慢速模式"
+	if req.Seconds == 0 {
+		message = "已关闭慢速模式"
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// SetTimezone 设置聊天室打卡使用的时区
+func (ctrl *ChatRoomController) SetTimezone(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64  `json:"operatorId" binding:"required"`
+		Timezone   string `json:"timezone" binding:"max=64"` // 为空表示使用校园默认时区
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.chatRoomService.SetTimezone(roomID, req.OperatorID, req.Timezone); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "时区设置成功"})
+}
+
+// KickMember 踢出成员（可同时封禁，防止立即重新加入）
+func (ctrl *ChatRoomController) KickMember(c *gin.Context) {
 	roomIDStr := c.Param("id")
 	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
 	if err != nil {
@@ -246,7 +441,9 @@ func (ctrl *ChatRoomController) UpdateMemberRole(c *gin.Context) {
 	var req struct {
 		OperatorID   int64  `json:"operatorId" binding:"required"`
 		TargetUserID int64  `json:"targetUserId" binding:"required"`
-		NewRole      string `json:"newRole" binding:"required,oneof=MEMBER ADMIN"`
+		Ban          bool   `json:"ban"`
+		BanMinutes   int    `json:"banMinutes" binding:"min=0"` // 0表示永久封禁
+		Reason       string `json:"reason" binding:"max=200"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -254,16 +451,24 @@ func (ctrl *ChatRoomController) UpdateMemberRole(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.chatRoomService.UpdateMemberRole(roomID, req.OperatorID, req.TargetUserID, req.NewRole); err != nil {
+	if req.Ban {
+		err = ctrl.chatRoomService.BanMember(roomID, req.OperatorID, req.TargetUserID, time.Duration(req.BanMinutes)*time.Minute, req.Reason)
+	} else {
+		err = ctrl.chatRoomService.KickMember(roomID, req.OperatorID, req.TargetUserID)
+	}
+	if err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "成员角色更新成功"})
+	// 断开被踢用户在该聊天室的连接
+	ctrl.webSocketHub.DisconnectUserFromRoom(roomID, req.TargetUserID, req.Reason)
+
+	c.JSON(http.StatusOK, gin.H{"message": "成员踢出成功"})
 }
 
-// MuteMember 禁言成员
-func (ctrl *ChatRoomController) MuteMember(c *gin.Context) {
+// TransferOwnership 转让房主
+func (ctrl *ChatRoomController) TransferOwnership(c *gin.Context) {
 	roomIDStr := c.Param("id")
 	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
 	if err != nil {
@@ -274,7 +479,6 @@ func (ctrl *ChatRoomController) MuteMember(c *gin.Context) {
 	var req struct {
 		OperatorID   int64 `json:"operatorId" binding:"required"`
 		TargetUserID int64 `json:"targetUserId" binding:"required"`
-		Muted        bool  `json:"muted"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -282,21 +486,127 @@ func (ctrl *ChatRoomController) MuteMember(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.chatRoomService.MuteMember(roomID, req.OperatorID, req.TargetUserID, req.Muted); err != nil {
+	if err := ctrl.chatRoomService.TransferOwnership(roomID, req.OperatorID, req.TargetUserID); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
 
-	message := "成员禁言成功"
-	if !req.Muted {
-		message = "成员解除禁言成功"
+	// 通知聊天室内的在线用户房主已变更
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":       "owner_transferred",
+		"roomId":     roomID,
+		"oldOwnerId": req.OperatorID,
+		"newOwnerId": req.TargetUserID,
+	})
+	ctrl.webSocketHub.BroadcastToRoom(roomID, messageData)
+
+	c.JSON(http.StatusOK, gin.H{"message": "房主转让成功"})
+}
+
+// SetJoinPolicy 设置聊天室加入方式
+func (ctrl *ChatRoomController) SetJoinPolicy(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64  `json:"operatorId" binding:"required"`
+		JoinPolicy string `json:"joinPolicy" binding:"required,oneof=OPEN APPROVAL INVITE"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.chatRoomService.SetJoinPolicy(roomID, req.OperatorID, req.JoinPolicy); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "加入方式设置成功"})
+}
+
+// GetJoinRequests 获取入群申请列表
+func (ctrl *ChatRoomController) GetJoinRequests(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return
+	}
+
+	// 默认只查看待审核的申请
+	status := c.DefaultQuery("status", "PENDING")
+
+	requests, err := ctrl.chatRoomService.GetJoinRequests(roomID, operatorID, status)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": requests})
+}
+
+// ReviewJoinRequest 审批入群申请
+func (ctrl *ChatRoomController) ReviewJoinRequest(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	requestIDStr := c.Param("request_id")
+	requestID, err := strconv.ParseInt(requestIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的申请ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64 `json:"operatorId" binding:"required"`
+		Approved   bool  `json:"approved"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := ctrl.chatRoomService.ReviewJoinRequest(roomID, requestID, req.OperatorID, req.Approved)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通知申请人审批结果
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":     "join_request_reviewed",
+		"roomId":   roomID,
+		"approved": req.Approved,
+	})
+	ctrl.webSocketHub.SendToUser(request.UserID, messageData)
+
+	message := "已同意入群申请"
+	if !req.Approved {
+		message = "已拒绝入群申请"
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": message})
 }
 
-// KickMember 踢出成员
-func (ctrl *ChatRoomController) KickMember(c *gin.Context) {
+// CreateInvite 创建邀请码
+func (ctrl *ChatRoomController) CreateInvite(c *gin.Context) {
 	roomIDStr := c.Param("id")
 	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
 	if err != nil {
@@ -305,8 +615,9 @@ func (ctrl *ChatRoomController) KickMember(c *gin.Context) {
 	}
 
 	var req struct {
-		OperatorID   int64 `json:"operatorId" binding:"required"`
-		TargetUserID int64 `json:"targetUserId" binding:"required"`
+		OperatorID     int64 `json:"operatorId" binding:"required"`
+		MaxUses        int   `json:"maxUses" binding:"min=0"`                // 0表示不限次数
+		ExpiresInHours int   `json:"expiresInHours" binding:"min=0,max=720"` // 0表示永不过期
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -314,10 +625,161 @@ func (ctrl *ChatRoomController) KickMember(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.chatRoomService.KickMember(roomID, req.OperatorID, req.TargetUserID); err != nil {
+	invite, err := ctrl.chatRoomService.CreateInvite(roomID, req.OperatorID, req.MaxUses, time.Duration(req.ExpiresInHours)*time.Hour)
+	if err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "成员踢出成功"})
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "邀请码创建成功",
+		"data":    invite,
+	})
+}
+
+// GetInvites 获取邀请码列表
+func (ctrl *ChatRoomController) GetInvites(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return
+	}
+
+	invites, err := ctrl.chatRoomService.GetInvites(roomID, operatorID)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": invites})
+}
+
+// RevokeInvite 作废邀请码
+func (ctrl *ChatRoomController) RevokeInvite(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	inviteIDStr := c.Param("invite_id")
+	inviteID, err := strconv.ParseInt(inviteIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的邀请码ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64 `json:"operatorId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.chatRoomService.RevokeInvite(roomID, inviteID, req.OperatorID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "邀请码已作废"})
+}
+
+// BanMember 封禁用户
+func (ctrl *ChatRoomController) BanMember(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID      int64  `json:"operatorId" binding:"required"`
+		TargetUserID    int64  `json:"targetUserId" binding:"required"`
+		DurationMinutes int    `json:"durationMinutes" binding:"min=0"` // 0表示永久封禁
+		Reason          string `json:"reason" binding:"max=200"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	if err := ctrl.chatRoomService.BanMember(roomID, req.OperatorID, req.TargetUserID, duration, req.Reason); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 断开被封禁用户在该聊天室的连接
+	ctrl.webSocketHub.DisconnectUserFromRoom(roomID, req.TargetUserID, req.Reason)
+
+	c.JSON(http.StatusOK, gin.H{"message": "用户封禁成功"})
+}
+
+// GetBans 获取封禁列表
+func (ctrl *ChatRoomController) GetBans(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return
+	}
+
+	bans, err := ctrl.chatRoomService.GetBans(roomID, operatorID)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": bans})
+}
+
+// UnbanUser 解除封禁
+func (ctrl *ChatRoomController) UnbanUser(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64 `json:"operatorId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.chatRoomService.UnbanUser(roomID, req.OperatorID, userID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "解除封禁成功"})
 }
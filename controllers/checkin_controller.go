@@ -1,9 +1,15 @@
 package controllers
 
 import (
+	"campus-canvas-chat/export"
 	"campus-canvas-chat/models"
 	"campus-canvas-chat/services"
+	"campus-canvas-chat/websocket"
+	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -11,12 +17,24 @@ import (
 )
 
 type CheckInController struct {
-	checkInService *services.CheckInService
+	checkInService     *services.CheckInService
+	makeupService      *services.CheckInMakeupService
+	feedService        *services.CheckInFeedService
+	analyticsService   *services.CheckInAnalyticsService
+	exportService      *services.CheckInExportService
+	achievementService *services.AchievementService
+	webSocketHub       *websocket.Hub
 }
 
-func NewCheckInController() *CheckInController {
+func NewCheckInController(webSocketHub *websocket.Hub) *CheckInController {
 	return &CheckInController{
-		checkInService: services.NewCheckInService(),
+		checkInService:     services.NewCheckInService(),
+		makeupService:      services.NewCheckInMakeupService(),
+		feedService:        services.NewCheckInFeedService(),
+		analyticsService:   services.NewCheckInAnalyticsService(),
+		exportService:      services.NewCheckInExportService(),
+		achievementService: services.NewAchievementService(),
+		webSocketHub:       webSocketHub,
 	}
 }
 
@@ -30,6 +48,13 @@ func (ctrl *CheckInController) CreateCheckInTask(c *gin.Context) {
 		StartDate   string `json:"startDate" binding:"required"`
 		EndDate     string `json:"endDate"`
 		OperatorID  int64  `json:"operatorId" binding:"required"`
+
+		// 补卡设置（可选）
+		MakeupWindowDays       int  `json:"makeupWindowDays" binding:"min=0,max=30"`      // 0表示不允许补卡
+		MakeupLimit            *int `json:"makeupLimit" binding:"omitempty,min=0,max=31"` // 每个周期最多补卡次数，默认1次，0表示不限制
+		MakeupRequiresApproval bool `json:"makeupRequiresApproval"`
+
+		ReminderTime string `json:"reminderTime"` // 周期最后一天的提醒时间（HH:MM），为空表示不提醒
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -37,8 +62,13 @@ func (ctrl *CheckInController) CreateCheckInTask(c *gin.Context) {
 		return
 	}
 
+	if !isValidReminderTime(req.ReminderTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的提醒时间格式"})
+		return
+	}
+
 	// 解析开始日期
-	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	startDate, err := parseDate(req.StartDate)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期格式"})
 		return
@@ -51,13 +81,22 @@ func (ctrl *CheckInController) CreateCheckInTask(c *gin.Context) {
 		Cycle:       req.Cycle,
 		IsActive:    true,
 		StartDate:   startDate,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+
+		MakeupWindowDays:       req.MakeupWindowDays,
+		MakeupLimit:            1,
+		MakeupRequiresApproval: req.MakeupRequiresApproval,
+		ReminderTime:           req.ReminderTime,
+
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if req.MakeupLimit != nil {
+		task.MakeupLimit = *req.MakeupLimit
 	}
 
 	// 解析结束日期（可选）
 	if req.EndDate != "" {
-		endDate, err := time.Parse("2006-01-02", req.EndDate)
+		endDate, err := parseDate(req.EndDate)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期格式"})
 			return
@@ -112,6 +151,12 @@ func (ctrl *CheckInController) UpdateCheckInTask(c *gin.Context) {
 		IsActive    *bool  `json:"isActive"`
 		EndDate     string `json:"endDate"`
 		OperatorID  int64  `json:"operatorId" binding:"required"`
+
+		MakeupWindowDays       *int  `json:"makeupWindowDays" binding:"omitempty,min=0,max=30"`
+		MakeupLimit            *int  `json:"makeupLimit" binding:"omitempty,min=0,max=31"`
+		MakeupRequiresApproval *bool `json:"makeupRequiresApproval"`
+
+		ReminderTime *string `json:"reminderTime"` // 为空字符串表示关闭提醒
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -132,8 +177,24 @@ func (ctrl *CheckInController) UpdateCheckInTask(c *gin.Context) {
 	if req.IsActive != nil {
 		updates["is_active"] = *req.IsActive
 	}
+	if req.MakeupWindowDays != nil {
+		updates["makeup_window_days"] = *req.MakeupWindowDays
+	}
+	if req.MakeupLimit != nil {
+		updates["makeup_limit"] = *req.MakeupLimit
+	}
+	if req.MakeupRequiresApproval != nil {
+		updates["makeup_requires_approval"] = *req.MakeupRequiresApproval
+	}
+	if req.ReminderTime != nil {
+		if !isValidReminderTime(*req.ReminderTime) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的提醒时间格式"})
+			return
+		}
+		updates["reminder_time"] = *req.ReminderTime
+	}
 	if req.EndDate != "" {
-		endDate, err := time.Parse("2006-01-02", req.EndDate)
+		endDate, err := parseDate(req.EndDate)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期格式"})
 			return
@@ -186,8 +247,13 @@ func (ctrl *CheckInController) DeleteCheckInTask(c *gin.Context) {
 func (ctrl *CheckInController) SubmitCheckIn(c *gin.Context) {
 	var req struct {
 		ChatRoomID int64  `json:"chatRoomId" binding:"required"`
+		TaskID     int64  `json:"taskId" binding:"required"`
 		UserID     int64  `json:"userId" binding:"required"`
 		Content    string `json:"content" binding:"max=500"`
+
+		DurationMinutes int      `json:"durationMinutes" binding:"min=0,max=1440"` // 学习时长（分钟）
+		Location        string   `json:"location" binding:"max=100"`               // 地点标签
+		Attachments     []string `json:"attachments" binding:"max=9,dive,max=500"` // 笔记照片等附件的URL
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -195,12 +261,25 @@ func (ctrl *CheckInController) SubmitCheckIn(c *gin.Context) {
 		return
 	}
 
+	attachments := make([]models.CheckInAttachment, 0, len(req.Attachments))
+	for _, attachmentURL := range req.Attachments {
+		if !isValidAttachmentURL(attachmentURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的附件地址"})
+			return
+		}
+		attachments = append(attachments, models.CheckInAttachment{URL: attachmentURL})
+	}
+
 	checkIn := &models.CheckIn{
-		ChatRoomID: req.ChatRoomID,
-		UserID:     req.UserID,
-		Content:    req.Content,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ChatRoomID:      req.ChatRoomID,
+		TaskID:          &req.TaskID,
+		UserID:          req.UserID,
+		Content:         req.Content,
+		DurationMinutes: req.DurationMinutes,
+		Location:        req.Location,
+		Attachments:     attachments,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 
 	if err := ctrl.checkInService.SubmitCheckIn(checkIn); err != nil {
@@ -208,6 +287,8 @@ func (ctrl *CheckInController) SubmitCheckIn(c *gin.Context) {
 		return
 	}
 
+	ctrl.awardBadges(checkIn.ID)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "打卡成功",
 		"data":    checkIn,
@@ -241,20 +322,26 @@ func (ctrl *CheckInController) GetCheckInRecords(c *gin.Context) {
 		}
 	}
 
+	// 可选的任务ID过滤
+	taskID, ok := queryTaskID(c)
+	if !ok {
+		return
+	}
+
 	// 可选的日期范围过滤
 	var startDate, endDate *time.Time
 	if startDateStr := c.Query("start_date"); startDateStr != "" {
-		if sd, err := time.Parse("2006-01-02", startDateStr); err == nil {
+		if sd, err := parseDate(startDateStr); err == nil {
 			startDate = &sd
 		}
 	}
 	if endDateStr := c.Query("end_date"); endDateStr != "" {
-		if ed, err := time.Parse("2006-01-02", endDateStr); err == nil {
+		if ed, err := parseDate(endDateStr); err == nil {
 			endDate = &ed
 		}
 	}
 
-	checkIns, total, err := ctrl.checkInService.GetCheckInRecords(roomID, userID, startDate, endDate, page, pageSize)
+	checkIns, total, err := ctrl.checkInService.GetCheckInRecords(roomID, userID, taskID, startDate, endDate, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -280,29 +367,69 @@ func (ctrl *CheckInController) GetCheckInStats(c *gin.Context) {
 		return
 	}
 
-	// 默认统计最近30天
-	startDateStr := c.DefaultQuery("start_date", time.Now().AddDate(0, 0, -30).Format("2006-01-02"))
-	endDateStr := c.DefaultQuery("end_date", time.Now().Format("2006-01-02"))
+	// 未指定日期范围时默认统计聊天室时区下的最近30天
+	startDate, endDate, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	taskID, ok := queryTaskID(c)
+	if !ok {
+		return
+	}
 
-	startDate, err := time.Parse("2006-01-02", startDateStr)
+	stats, err := ctrl.checkInService.GetCheckInStats(roomID, taskID, startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期格式"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	endDate, err := time.Parse("2006-01-02", endDateStr)
+	c.JSON(http.StatusOK, gin.H{"data": stats})
+}
+
+// GetCheckInLeaderboard 获取打卡排行榜，可按打卡次数、学习总时长或当前连续打卡排名
+func (ctrl *CheckInController) GetCheckInLeaderboard(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期格式"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	mode := c.DefaultQuery("mode", "COUNT")
+	if mode != "COUNT" && mode != "DURATION" && mode != "STREAK" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的排名方式"})
+		return
+	}
+
+	// 未指定日期范围时默认统计聊天室时区下的最近30天（连续打卡排名不受日期范围影响）
+	startDate, endDate, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	// 可选的查询者ID，用于返回本人排名
+	var viewerID *int64
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		if uid, err := strconv.ParseInt(userIDStr, 10, 64); err == nil {
+			viewerID = &uid
+		}
+	}
+
+	taskID, ok := queryTaskID(c)
+	if !ok {
 		return
 	}
 
-	stats, err := ctrl.checkInService.GetCheckInStats(roomID, startDate, endDate)
+	page, pageSize := parsePage(c)
+
+	leaderboard, err := ctrl.checkInService.GetCheckInLeaderboard(roomID, taskID, mode, startDate, endDate, page, pageSize, viewerID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": stats})
+	c.JSON(http.StatusOK, gin.H{"data": leaderboard})
 }
 
 // GetUserCheckInHistory 获取用户打卡历史
@@ -322,16 +449,31 @@ func (ctrl *CheckInController) GetUserCheckInHistory(c *gin.Context) {
 		return
 	}
 
-	// 默认查询当前月份
-	month := c.DefaultQuery("month", time.Now().Format("2006-01"))
+	// 未指定时查询聊天室时区下的当前月份
+	month := c.Query("month")
+
+	taskID, ok := queryTaskID(c)
+	if !ok {
+		return
+	}
 
-	checkIns, err := ctrl.checkInService.GetUserCheckInHistory(roomID, userID, month)
+	checkIns, err := ctrl.checkInService.GetUserCheckInHistory(roomID, userID, taskID, month)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": checkIns})
+	// 用户在各任务上的连续打卡周期数
+	streaks, err := ctrl.checkInService.GetCheckInStreaks(roomID, &userID, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    checkIns,
+		"streaks": streaks,
+	})
 }
 
 // GetTodayCheckInStatus 获取今天的打卡状态
@@ -351,7 +493,18 @@ func (ctrl *CheckInController) GetTodayCheckInStatus(c *gin.Context) {
 		return
 	}
 
-	checkedIn, checkIn, err := ctrl.checkInService.GetTodayCheckInStatus(roomID, userID)
+	taskID, ok := queryTaskID(c)
+	if !ok {
+		return
+	}
+
+	checkedIn, checkIn, err := ctrl.checkInService.GetTodayCheckInStatus(roomID, userID, taskID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	streaks, err := ctrl.checkInService.GetCheckInStreaks(roomID, &userID, taskID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -361,6 +514,783 @@ func (ctrl *CheckInController) GetTodayCheckInStatus(c *gin.Context) {
 		"data": gin.H{
 			"checkedIn": checkedIn,
 			"checkIn":   checkIn,
+			"streaks":   streaks,
+		},
+	})
+}
+
+// queryTaskID 解析可选的task_id查询参数，格式错误时返回400
+func queryTaskID(c *gin.Context) (*int64, bool) {
+	taskIDStr := c.Query("task_id")
+	if taskIDStr == "" {
+		return nil, true
+	}
+
+	taskID, err := strconv.ParseInt(taskIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return nil, false
+	}
+	return &taskID, true
+}
+
+// SubmitMakeup 提交补卡申请
+func (ctrl *CheckInController) SubmitMakeup(c *gin.Context) {
+	var req struct {
+		ChatRoomID int64  `json:"chatRoomId" binding:"required"`
+		TaskID     int64  `json:"taskId" binding:"required"`
+		UserID     int64  `json:"userId" binding:"required"`
+		CheckDate  string `json:"checkDate" binding:"required"` // 补卡日期，格式YYYY-MM-DD
+		Content    string `json:"content" binding:"max=500"`
+		Reason     string `json:"reason" binding:"max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checkDate, err := parseDate(req.CheckDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的补卡日期格式"})
+		return
+	}
+
+	makeup := &models.CheckInMakeup{
+		ChatRoomID: req.ChatRoomID,
+		TaskID:     req.TaskID,
+		UserID:     req.UserID,
+		CheckDate:  checkDate,
+		Content:    req.Content,
+		Reason:     req.Reason,
+	}
+
+	if err := ctrl.makeupService.SubmitMakeup(makeup); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 无需审批的补卡直接通过，同样检查成就
+	if makeup.CheckInID != nil {
+		ctrl.awardBadges(*makeup.CheckInID)
+	}
+
+	message := "补卡成功"
+	if makeup.Status == "PENDING" {
+		message = "补卡申请已提交，等待审批"
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": message,
+		"data":    makeup,
+	})
+}
+
+// GetRoomMakeups 获取聊天室的补卡申请（房主和管理员）
+func (ctrl *CheckInController) GetRoomMakeups(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return
+	}
+
+	page, pageSize := parsePage(c)
+	makeups, total, err := ctrl.makeupService.GetRoomMakeups(roomID, operatorID, c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondMakeups(c, makeups, total, page, pageSize)
+}
+
+// GetUserMakeups 获取用户自己的补卡申请
+func (ctrl *CheckInController) GetUserMakeups(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	page, pageSize := parsePage(c)
+	makeups, total, err := ctrl.makeupService.GetUserMakeups(roomID, userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondMakeups(c, makeups, total, page, pageSize)
+}
+
+// ReviewMakeup 审批补卡申请
+func (ctrl *CheckInController) ReviewMakeup(c *gin.Context) {
+	makeupIDStr := c.Param("id")
+	makeupID, err := strconv.ParseInt(makeupIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的补卡申请ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64  `json:"operatorId" binding:"required"`
+		Approve    *bool  `json:"approve" binding:"required"`
+		Note       string `json:"note" binding:"max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	makeup, err := ctrl.makeupService.ReviewMakeup(makeupID, req.OperatorID, *req.Approve, req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if makeup.CheckInID != nil {
+		ctrl.awardBadges(*makeup.CheckInID)
+	}
+
+	message := "已通过补卡申请"
+	if !*req.Approve {
+		message = "已拒绝补卡申请"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    makeup,
+	})
+}
+
+// SetReminderEnabled 开启或关闭打卡提醒
+func (ctrl *CheckInController) SetReminderEnabled(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.checkInService.SetReminderEnabled(roomID, userID, *req.Enabled); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "已开启打卡提醒"
+	if !*req.Enabled {
+		message = "已关闭打卡提醒"
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// GetCheckInFeed 获取聊天室打卡动态
+func (ctrl *CheckInController) GetCheckInFeed(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	viewerID, err := strconv.ParseInt(c.Query("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	taskID, ok := queryTaskID(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := parsePage(c)
+
+	items, total, err := ctrl.feedService.GetRoomFeed(roomID, viewerID, taskID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"checkIns":  items,
+			"total":     total,
+			"page":      page,
+			"pageSize":  pageSize,
+			"totalPage": (total + int64(pageSize) - 1) / int64(pageSize),
 		},
 	})
 }
+
+// LikeCheckIn 点赞打卡，并通知打卡者
+func (ctrl *CheckInController) LikeCheckIn(c *gin.Context) {
+	checkInID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的打卡ID"})
+		return
+	}
+
+	var req struct {
+		UserID int64 `json:"userId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checkIn, liked, err := ctrl.feedService.LikeCheckIn(checkInID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通知打卡者（重复点赞和给自己点赞不通知）
+	if liked && checkIn.UserID != req.UserID {
+		messageData, _ := json.Marshal(map[string]interface{}{
+			"type":      "checkin_liked",
+			"roomId":    checkIn.ChatRoomID,
+			"checkInId": checkIn.ID,
+			"userId":    req.UserID,
+			"likeCount": checkIn.LikeCount,
+		})
+		ctrl.webSocketHub.DeliverToUser(checkIn.UserID, messageData)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "点赞成功",
+		"data":    gin.H{"likeCount": checkIn.LikeCount},
+	})
+}
+
+// UnlikeCheckIn 取消点赞
+func (ctrl *CheckInController) UnlikeCheckIn(c *gin.Context) {
+	checkInID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的打卡ID"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	if err := ctrl.feedService.UnlikeCheckIn(checkInID, userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已取消点赞"})
+}
+
+// GetCheckInLikes 获取打卡的点赞列表
+func (ctrl *CheckInController) GetCheckInLikes(c *gin.Context) {
+	checkInID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的打卡ID"})
+		return
+	}
+
+	viewerID, err := strconv.ParseInt(c.Query("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	likes, err := ctrl.feedService.GetLikes(checkInID, viewerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": likes})
+}
+
+// AddCheckInComment 评论打卡，并通知打卡者
+func (ctrl *CheckInController) AddCheckInComment(c *gin.Context) {
+	checkInID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的打卡ID"})
+		return
+	}
+
+	var req struct {
+		UserID  int64  `json:"userId" binding:"required"`
+		Content string `json:"content" binding:"required,min=1,max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, checkIn, err := ctrl.feedService.AddComment(checkInID, req.UserID, req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if checkIn.UserID != req.UserID {
+		messageData, _ := json.Marshal(map[string]interface{}{
+			"type":    "checkin_commented",
+			"roomId":  checkIn.ChatRoomID,
+			"comment": comment,
+		})
+		ctrl.webSocketHub.DeliverToUser(checkIn.UserID, messageData)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "评论成功",
+		"data":    comment,
+	})
+}
+
+// GetCheckInComments 获取打卡评论
+func (ctrl *CheckInController) GetCheckInComments(c *gin.Context) {
+	checkInID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的打卡ID"})
+		return
+	}
+
+	viewerID, err := strconv.ParseInt(c.Query("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	page, pageSize := parsePage(c)
+
+	comments, total, err := ctrl.feedService.GetComments(checkInID, viewerID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"comments":  comments,
+			"total":     total,
+			"page":      page,
+			"pageSize":  pageSize,
+			"totalPage": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// DeleteCheckInComment 删除打卡评论
+func (ctrl *CheckInController) DeleteCheckInComment(c *gin.Context) {
+	commentID, err := strconv.ParseInt(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论ID"})
+		return
+	}
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return
+	}
+
+	if err := ctrl.feedService.DeleteComment(commentID, operatorID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "评论删除成功"})
+}
+
+// ExportCheckInRecords 导出打卡记录（CSV或XLSX，房主和管理员）
+func (ctrl *CheckInController) ExportCheckInRecords(c *gin.Context) {
+	filter, format, ok := ctrl.parseExportRequest(c)
+	if !ok {
+		return
+	}
+
+	ctrl.writeExport(c, format, fmt.Sprintf("checkins-%d", filter.ChatRoomID), func(w export.RowWriter) error {
+		if err := w.WriteRow("打卡ID", "打卡日期", "用户ID", "用户名", "任务ID", "任务", "内容", "学习时长（分钟）", "地点", "补卡", "点赞数", "评论数", "提交时间"); err != nil {
+			return err
+		}
+		return ctrl.exportService.StreamRecords(filter, func(row services.CheckInRecordRow) error {
+			var taskID, taskTitle interface{} = "", ""
+			if row.TaskID != nil {
+				taskID = *row.TaskID
+			}
+			if row.TaskTitle != nil {
+				taskTitle = *row.TaskTitle
+			}
+			return w.WriteRow(row.ID, row.CheckDate, row.UserID, row.Username, taskID, taskTitle, row.Content,
+				row.DurationMinutes, row.Location, row.IsMakeup, row.LikeCount, row.CommentCount,
+				row.CreatedAt.Format("2006-01-02 15:04:05"))
+		})
+	})
+}
+
+// ExportCheckInStats 导出成员打卡统计（CSV或XLSX，房主和管理员）
+func (ctrl *CheckInController) ExportCheckInStats(c *gin.Context) {
+	filter, format, ok := ctrl.parseExportRequest(c)
+	if !ok {
+		return
+	}
+
+	ctrl.writeExport(c, format, fmt.Sprintf("checkin-stats-%d", filter.ChatRoomID), func(w export.RowWriter) error {
+		if err := w.WriteRow("用户ID", "用户名", "打卡次数", "补卡次数", "学习总时长（分钟）", "首次打卡", "最近打卡"); err != nil {
+			return err
+		}
+		return ctrl.exportService.StreamStats(filter, func(row services.CheckInStatsRow) error {
+			return w.WriteRow(row.UserID, row.Username, row.CheckInCount, row.MakeupCount, row.TotalDuration, row.FirstCheck, row.LastCheck)
+		})
+	})
+}
+
+// parseExportRequest 解析导出的过滤条件和格式，并检查操作者权限，失败时直接返回错误响应
+func (ctrl *CheckInController) parseExportRequest(c *gin.Context) (services.CheckInExportFilter, string, bool) {
+	var filter services.CheckInExportFilter
+
+	roomID, err := strconv.ParseInt(c.Param("room_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return filter, "", false
+	}
+	filter.ChatRoomID = roomID
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return filter, "", false
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的导出格式"})
+		return filter, "", false
+	}
+
+	// 可选的用户ID过滤
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		if uid, err := strconv.ParseInt(userIDStr, 10, 64); err == nil {
+			filter.UserID = &uid
+		}
+	}
+
+	var ok bool
+	filter.TaskID, ok = queryTaskID(c)
+	if !ok {
+		return filter, "", false
+	}
+
+	filter.StartDate, filter.EndDate, ok = parseDateRange(c)
+	if !ok {
+		return filter, "", false
+	}
+
+	if err := ctrl.exportService.CheckAccess(roomID, operatorID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return filter, "", false
+	}
+
+	return filter, format, true
+}
+
+// writeExport 设置下载响应头并把导出内容直接写入响应，开始写出后出错只能记录日志
+func (ctrl *CheckInController) writeExport(c *gin.Context, format, name string, write func(w export.RowWriter) error) {
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102"), format)
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	writer, err := export.NewWriter(format, c.Writer)
+	if err == nil {
+		err = write(writer)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Printf("导出打卡数据失败: %v", err)
+	}
+}
+
+// GetUserHeatmap 获取用户打卡日历热力图
+func (ctrl *CheckInController) GetUserHeatmap(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	userIDStr := c.Param("user_id")
+
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	// 可选的年份，未指定时返回最近一年
+	year := 0
+	if yearStr := c.Query("year"); yearStr != "" {
+		year, err = strconv.Atoi(yearStr)
+		if err != nil || year < 2000 || year > 9999 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的年份"})
+			return
+		}
+	}
+
+	days, err := ctrl.analyticsService.GetUserHeatmap(roomID, userID, year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": days})
+}
+
+// GetUserCompletion 获取用户各打卡任务的完成率
+func (ctrl *CheckInController) GetUserCompletion(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	userIDStr := c.Param("user_id")
+
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	completions, err := ctrl.analyticsService.GetUserCompletion(roomID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": completions})
+}
+
+// GetRoomCompletion 获取聊天室各打卡任务的整体完成率
+func (ctrl *CheckInController) GetRoomCompletion(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	completions, err := ctrl.analyticsService.GetRoomCompletion(roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": completions})
+}
+
+// GetMissedToday 获取当前周期尚未打卡的成员（房主和管理员）
+func (ctrl *CheckInController) GetMissedToday(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return
+	}
+
+	taskID, ok := queryTaskID(c)
+	if !ok {
+		return
+	}
+
+	missed, err := ctrl.analyticsService.GetMissedToday(roomID, operatorID, taskID)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": missed})
+}
+
+// GetParticipationTrend 获取聊天室每日打卡参与趋势
+func (ctrl *CheckInController) GetParticipationTrend(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	startDate, endDate, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	days, err := ctrl.analyticsService.GetParticipationTrend(roomID, startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": days})
+}
+
+// GetBadgeRules 获取所有成就徽章的定义
+func (ctrl *CheckInController) GetBadgeRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": ctrl.achievementService.GetBadgeRules()})
+}
+
+// GetUserBadges 获取用户在聊天室获得的徽章
+func (ctrl *CheckInController) GetUserBadges(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	userIDStr := c.Param("user_id")
+
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	badges, err := ctrl.achievementService.GetUserBadges(roomID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": badges})
+}
+
+// awardBadges 检查打卡是否达成成就，并向聊天室广播新获得的徽章（失败不影响打卡结果）
+func (ctrl *CheckInController) awardBadges(checkInID int64) {
+	badges, err := ctrl.achievementService.EvaluateCheckIn(checkInID)
+	if err != nil {
+		log.Printf("检查打卡成就失败: %v", err)
+	}
+
+	for _, badge := range badges {
+		messageData, _ := json.Marshal(map[string]interface{}{
+			"type":   "badge_awarded",
+			"roomId": badge.ChatRoomID,
+			"userId": badge.UserID,
+			"badge":  badge,
+		})
+		ctrl.webSocketHub.BroadcastToRoom(badge.ChatRoomID, messageData)
+	}
+}
+
+// parsePage 解析列表的分页参数
+func parsePage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// respondMakeups 返回分页的补卡申请列表
+func respondMakeups(c *gin.Context, makeups []models.CheckInMakeup, total int64, page, pageSize int) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"makeups":   makeups,
+			"total":     total,
+			"page":      page,
+			"pageSize":  pageSize,
+			"totalPage": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// parseDateRange 解析可选的start_date和end_date查询参数，格式错误时直接返回400
+func parseDateRange(c *gin.Context) (startDate, endDate *time.Time, ok bool) {
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		sd, err := parseDate(startDateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期格式"})
+			return nil, nil, false
+		}
+		startDate = &sd
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		ed, err := parseDate(endDateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期格式"})
+			return nil, nil, false
+		}
+		endDate = &ed
+	}
+	return startDate, endDate, true
+}
+
+// parseDate 解析YYYY-MM-DD格式的日期，与数据库DATE字段使用相同的时区
+func parseDate(value string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// isValidReminderTime 检查提醒时间是否为空或HH:MM格式
+func isValidReminderTime(value string) bool {
+	if value == "" {
+		return true
+	}
+	_, err := time.Parse("15:04", value)
+	return err == nil
+}
+
+// isValidAttachmentURL 检查附件地址是否为http(s)链接
+func isValidAttachmentURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
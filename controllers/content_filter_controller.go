@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"campus-canvas-chat/middleware"
+	"campus-canvas-chat/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContentFilterController struct {
+	contentFilterService *services.ContentFilterService
+}
+
+func NewContentFilterController() *ContentFilterController {
+	return &ContentFilterController{
+		contentFilterService: services.NewContentFilterService(),
+	}
+}
+
+// GetSensitiveWords 获取敏感词列表
+func (ctrl *ContentFilterController) GetSensitiveWords(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	keyword := c.Query("keyword")
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	words, total, err := ctrl.contentFilterService.GetSensitiveWords(keyword, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"words":      words,
+			"total":      total,
+			"page":       page,
+			"page_size":  pageSize,
+			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// CreateSensitiveWord 添加敏感词
+func (ctrl *ContentFilterController) CreateSensitiveWord(c *gin.Context) {
+	var req struct {
+		Word   string `json:"word" binding:"required,max=100"`
+		Action string `json:"action" binding:"required,oneof=REJECT MASK FLAG"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin := middleware.GetAdmin(c)
+	word, err := ctrl.contentFilterService.CreateSensitiveWord(admin.UserID, req.Word, req.Action)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "敏感词添加成功",
+		"data":    word,
+	})
+}
+
+// UpdateSensitiveWord 修改敏感词
+func (ctrl *ContentFilterController) UpdateSensitiveWord(c *gin.Context) {
+	wordIDStr := c.Param("word_id")
+	wordID, err := strconv.ParseInt(wordIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的敏感词ID"})
+		return
+	}
+
+	var req struct {
+		Action   string `json:"action" binding:"omitempty,oneof=REJECT MASK FLAG"`
+		IsActive *bool  `json:"isActive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Action != "" {
+		updates["action"] = req.Action
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的字段"})
+		return
+	}
+
+	updates["updated_at"] = time.Now()
+
+	admin := middleware.GetAdmin(c)
+	if err := ctrl.contentFilterService.UpdateSensitiveWord(admin.UserID, wordID, updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "敏感词更新成功"})
+}
+
+// DeleteSensitiveWord 删除敏感词
+func (ctrl *ContentFilterController) DeleteSensitiveWord(c *gin.Context) {
+	wordIDStr := c.Param("word_id")
+	wordID, err := strconv.ParseInt(wordIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的敏感词ID"})
+		return
+	}
+
+	admin := middleware.GetAdmin(c)
+	if err := ctrl.contentFilterService.DeleteSensitiveWord(admin.UserID, wordID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "敏感词删除成功"})
+}
+
+// GetFilterHits 获取敏感词命中记录
+func (ctrl *ContentFilterController) GetFilterHits(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	reviewStatus := c.DefaultQuery("status", "PENDING")
+	source := c.Query("source")
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	hits, total, err := ctrl.contentFilterService.GetFilterHits(reviewStatus, source, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"hits":       hits,
+			"total":      total,
+			"page":       page,
+			"page_size":  pageSize,
+			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// ReviewFilterHit 标记命中记录为已审核
+func (ctrl *ContentFilterController) ReviewFilterHit(c *gin.Context) {
+	hitIDStr := c.Param("hit_id")
+	hitID, err := strconv.ParseInt(hitIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的命中记录ID"})
+		return
+	}
+
+	admin := middleware.GetAdmin(c)
+	if err := ctrl.contentFilterService.ReviewFilterHit(admin.UserID, hitID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已标记为已审核"})
+}
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"campus-canvas-chat/services"
+	"campus-canvas-chat/websocket"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConversationController struct {
+	conversationService *services.ConversationService
+	rateLimitService    *services.RateLimitService
+	webSocketHub        *websocket.Hub
+}
+
+func NewConversationController(webSocketHub *websocket.Hub) *ConversationController {
+	return &ConversationController{
+		conversationService: services.NewConversationService(),
+		rateLimitService:    services.NewRateLimitService(),
+		webSocketHub:        webSocketHub,
+	}
+}
+
+// CreateGroupConversation 创建多人会话
+func (ctrl *ConversationController) CreateGroupConversation(c *gin.Context) {
+	var req struct {
+		CreatorID int64   `json:"creatorId" binding:"required"`
+		Name      string  `json:"name" binding:"max=100"`
+		MemberIDs []int64 `json:"memberIds" binding:"required,min=2,max=9"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, err := ctrl.conversationService.CreateGroupConversation(req.CreatorID, req.Name, req.MemberIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通知被拉入会话的用户
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":         "conversation_created",
+		"conversation": conversation,
+	})
+	for _, participant := range conversation.Participants {
+		if participant.UserID != req.CreatorID {
+			ctrl.webSocketHub.SendToUser(participant.UserID, messageData)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "多人会话创建成功",
+		"data":    conversation,
+	})
+}
+
+// GetConversation 获取会话详情
+func (ctrl *ConversationController) GetConversation(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("conversation_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Query("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	conversation, err := ctrl.conversationService.GetConversation(conversationID, userID)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": conversation})
+}
+
+// RenameConversation 修改多人会话名称
+func (ctrl *ConversationController) RenameConversation(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("conversation_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64  `json:"operatorId" binding:"required"`
+		Name       string `json:"name" binding:"required,max=100"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.conversationService.RenameConversation(conversationID, req.OperatorID, req.Name); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "会话名称修改成功"})
+}
+
+// AddParticipants 邀请用户加入多人会话
+func (ctrl *ConversationController) AddParticipants(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("conversation_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64   `json:"operatorId" binding:"required"`
+		UserIDs    []int64 `json:"userIds" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	participants, err := ctrl.conversationService.AddParticipants(conversationID, req.OperatorID, req.UserIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通知新加入的用户
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":           "conversation_joined",
+		"conversationId": conversationID,
+	})
+	for _, participant := range participants {
+		ctrl.webSocketHub.SendToUser(participant.UserID, messageData)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "成员添加成功",
+		"data":    participants,
+	})
+}
+
+// RemoveParticipant 移出成员或退出多人会话
+func (ctrl *ConversationController) RemoveParticipant(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("conversation_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	targetUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return
+	}
+
+	if err := ctrl.conversationService.RemoveParticipant(conversationID, operatorID, targetUserID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "成员已移出"
+	if targetUserID == operatorID {
+		message = "已退出会话"
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// SendConversationMessage 在多人会话中发送消息
+func (ctrl *ConversationController) SendConversationMessage(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("conversation_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	var req struct {
+		SenderID int64  `json:"senderId" binding:"required"`
+		Content  string `json:"content" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	// 先检查发言权限，被拒绝的发送不占用频率限制
+	if err := ctrl.conversationService.CheckSendPermission(conversationID, req.SenderID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 检查发送频率
+	if !checkSendRate(c, ctrl.rateLimitService, req.SenderID, nil) {
+		return
+	}
+
+	message, recipientIDs, err := ctrl.conversationService.SendConversationMessage(conversationID, req.SenderID, req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 推送给会话内的其他在线参与者
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":           "conversation_message",
+		"conversationId": conversationID,
+		"message":        message,
+	})
+	for _, userID := range recipientIDs {
+		ctrl.webSocketHub.SendToUser(userID, messageData)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "消息发送成功",
+		"data":    message,
+	})
+}
+
+// GetConversationMessages 获取会话消息列表
+func (ctrl *ConversationController) GetConversationMessages(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("conversation_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Query("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	messages, total, err := ctrl.conversationService.GetConversationMessages(conversationID, userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取会话消息成功",
+		"data": gin.H{
+			"messages":  messages,
+			"page":      page,
+			"pageSize":  pageSize,
+			"total":     total,
+			"totalPage": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"campus-canvas-chat/services"
 	"campus-canvas-chat/websocket"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -11,23 +13,48 @@ import (
 )
 
 type MessageController struct {
-	messageService *services.MessageService
-	webSocketHub   *websocket.Hub
+	messageService   *services.MessageService
+	rateLimitService *services.RateLimitService
+	webSocketHub     *websocket.Hub
 }
 
 func NewMessageController(messageService *services.MessageService, webSocketHub *websocket.Hub) *MessageController {
 	return &MessageController{
-		messageService: messageService,
-		webSocketHub:   webSocketHub,
+		messageService:   messageService,
+		rateLimitService: services.NewRateLimitService(),
+		webSocketHub:     webSocketHub,
 	}
 }
 
+// checkSendRate 检查发送频率，超限时返回429并设置Retry-After
+func checkSendRate(c *gin.Context, rateLimitService *services.RateLimitService, userID int64, chatRoomID *int64) bool {
+	err := rateLimitService.CheckSendRate(userID, chatRoomID, c.ClientIP())
+	if err == nil {
+		return true
+	}
+
+	var rateLimitErr *services.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		retryAfter := int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":      rateLimitErr.Message,
+			"retryAfter": retryAfter,
+		})
+		return false
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	return false
+}
+
 // SendGroupMessage 发送群聊消息
 func (mc *MessageController) SendGroupMessage(c *gin.Context) {
 	type SendGroupMessageRequest struct {
-		ChatRoomId int64  `json:"chatRoomId" binding:"required"`
-		UserId     int64  `json:"userId" binding:"required"`
-		Content    string `json:"content" binding:"required"`
+		ChatRoomId  int64  `json:"chatRoomId" binding:"required"`
+		UserId      int64  `json:"userId" binding:"required"`
+		Content     string `json:"content" binding:"required"`
+		ClientMsgId string `json:"clientMsgId" binding:"max=64"` // 可选，客户端生成的消息ID，重试时返回原消息
 	}
 
 	var req SendGroupMessageRequest
@@ -36,23 +63,53 @@ func (mc *MessageController) SendGroupMessage(c *gin.Context) {
 		return
 	}
 
+	// 先检查发言权限，被拒绝的发送不占用频率限制
+	if err := mc.messageService.CheckGroupSendPermission(req.ChatRoomId, req.UserId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 客户端重试已发送成功的消息时直接返回原消息，不占用频率限制
+	if req.ClientMsgId != "" {
+		if message, ok := mc.messageService.FindGroupMessageByClientMsgID(req.UserId, req.ClientMsgId); ok {
+			c.JSON(http.StatusOK, gin.H{
+				"message":   "群聊消息发送成功",
+				"data":      message,
+				"duplicate": true,
+			})
+			return
+		}
+	}
+
+	// 检查发送频率
+	if !checkSendRate(c, mc.rateLimitService, req.UserId, &req.ChatRoomId) {
+		return
+	}
+
 	// 发送群聊消息（持久化存储）
-	message, err := mc.messageService.SendGroupMessage(req.ChatRoomId, req.UserId, req.Content)
+	message, duplicate, err := mc.messageService.SendGroupMessage(req.ChatRoomId, req.UserId, req.Content, req.ClientMsgId)
 	if err != nil {
+		// 发送失败不占用慢速模式间隔
+		mc.rateLimitService.ReleaseSlowMode(req.ChatRoomId, req.UserId)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 通过WebSocket广播消息给聊天室内的在线用户
-	messageData, _ := json.Marshal(map[string]interface{}{
-		"type":    "group_message",
-		"message": message,
-	})
-	mc.webSocketHub.BroadcastToRoom(req.ChatRoomId, messageData)
+	// 通过WebSocket广播消息给聊天室内的在线用户（重复提交时不再广播，也不占用慢速模式间隔）
+	if duplicate {
+		mc.rateLimitService.ReleaseSlowMode(req.ChatRoomId, req.UserId)
+	} else {
+		messageData, _ := json.Marshal(map[string]interface{}{
+			"type":    "group_message",
+			"message": message,
+		})
+		mc.webSocketHub.BroadcastGroupMessage(req.ChatRoomId, req.UserId, messageData)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "群聊消息发送成功",
-		"data":    message,
+		"message":   "群聊消息发送成功",
+		"data":      message,
+		"duplicate": duplicate,
 	})
 }
 
@@ -82,8 +139,19 @@ func (mc *MessageController) GetGroupMessages(c *gin.Context) {
 		return
 	}
 
+	// 可选的查看者ID，用于隐藏已拉黑用户的消息
+	var viewerID *int64
+	if userIDStr := c.Query("userId"); userIDStr != "" {
+		userID, err := strconv.ParseInt(userIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+			return
+		}
+		viewerID = &userID
+	}
+
 	// 获取群聊消息
-	messages, total, err := mc.messageService.GetGroupMessages(chatRoomId, page, pageSize)
+	messages, total, err := mc.messageService.GetGroupMessages(chatRoomId, viewerID, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -107,9 +175,10 @@ func (mc *MessageController) GetGroupMessages(c *gin.Context) {
 // SendPrivateMessage 发送私聊消息
 func (mc *MessageController) SendPrivateMessage(c *gin.Context) {
 	type SendPrivateMessageRequest struct {
-		SenderId   int64  `json:"senderId" binding:"required"`
-		ReceiverId int64  `json:"receiverId" binding:"required"`
-		Content    string `json:"content" binding:"required"`
+		SenderId    int64  `json:"senderId" binding:"required"`
+		ReceiverId  int64  `json:"receiverId" binding:"required"`
+		Content     string `json:"content" binding:"required"`
+		ClientMsgId string `json:"clientMsgId" binding:"max=64"` // 可选，客户端生成的消息ID，重试时返回原消息
 	}
 
 	var req SendPrivateMessageRequest
@@ -118,25 +187,53 @@ func (mc *MessageController) SendPrivateMessage(c *gin.Context) {
 		return
 	}
 
+	// 先检查私信权限，被拒绝的发送不占用频率限制
+	if err := mc.messageService.CheckPrivateSendPermission(req.SenderId, req.ReceiverId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 客户端重试已发送成功的消息时直接返回原消息，不占用频率限制
+	if req.ClientMsgId != "" {
+		if message, ok := mc.messageService.FindPrivateMessageByClientMsgID(req.SenderId, req.ClientMsgId); ok {
+			c.JSON(http.StatusOK, gin.H{
+				"message":   "私聊消息发送成功",
+				"createdAt": message.CreatedAt,
+				"data":      message,
+				"duplicate": true,
+			})
+			return
+		}
+	}
+
+	// 检查发送频率
+	if !checkSendRate(c, mc.rateLimitService, req.SenderId, nil) {
+		return
+	}
+
 	// 发送私聊消息（持久化存储）
-	message, err := mc.messageService.SendPrivateMessage(req.SenderId, req.ReceiverId, req.Content)
+	message, duplicate, err := mc.messageService.SendPrivateMessage(req.SenderId, req.ReceiverId, req.Content, req.ClientMsgId)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 通过WebSocket推送给接收者（如果在线）
-	privateMessageData, _ := json.Marshal(map[string]interface{}{
-		"type":      "private_message",
-		"content":   message.Content,
-		"createdAt": message.CreatedAt,
-		"senderId":  message.SenderID,
-	})
-	mc.webSocketHub.SendToUser(req.ReceiverId, privateMessageData)
+	// 通过WebSocket推送给接收者（如果在线，重复提交时不再推送）
+	if !duplicate {
+		privateMessageData, _ := json.Marshal(map[string]interface{}{
+			"type":      "private_message",
+			"content":   message.Content,
+			"createdAt": message.CreatedAt,
+			"senderId":  message.SenderID,
+		})
+		mc.webSocketHub.SendToUser(req.ReceiverId, privateMessageData)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "私聊消息发送成功",
 		"createdAt": message.CreatedAt,
+		"data":      message,
+		"duplicate": duplicate,
 	})
 }
 
@@ -205,23 +302,29 @@ func (mc *MessageController) GetConversations(c *gin.Context) {
 		return
 	}
 
-	// 获取分页参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	// 获取分页参数（游标分页，cursor为上一页返回的nextCursor）
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	cursor := c.Query("cursor")
+
+	// 是否查看已归档的会话
+	archived := c.Query("archived") == "true"
 
 	// 获取会话列表
-	conversations, err := mc.messageService.GetConversations(userID)
+	conversations, nextCursor, err := mc.messageService.GetConversations(userID, archived, cursor, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取会话列表失败: " + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "获取会话列表失败: " + err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "获取会话列表成功",
 		"conversations": conversations,
-		"total":         len(conversations),
-		"page":          page,
 		"limit":         limit,
+		"nextCursor":    nextCursor,
+		"hasMore":       nextCursor != "",
 	})
 }
 
@@ -278,6 +381,72 @@ func (mc *MessageController) ClearConversationUnreadCount(c *gin.Context) {
 	})
 }
 
+// UpdateConversationSettings 设置会话免打扰、置顶、归档
+func (mc *MessageController) UpdateConversationSettings(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("conversation_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	var req struct {
+		UserID   int64 `json:"userId" binding:"required"`
+		Muted    *bool `json:"muted"`
+		Pinned   *bool `json:"pinned"`
+		Archived *bool `json:"archived"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Muted != nil {
+		updates["is_muted"] = *req.Muted
+	}
+	if req.Pinned != nil {
+		updates["is_pinned"] = *req.Pinned
+	}
+	if req.Archived != nil {
+		updates["is_archived"] = *req.Archived
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的字段"})
+		return
+	}
+
+	if err := mc.messageService.UpdateConversationSettings(conversationID, req.UserID, updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "会话设置更新成功"})
+}
+
+// DeleteConversationForMe 删除会话（仅对自己生效）
+func (mc *MessageController) DeleteConversationForMe(c *gin.Context) {
+	conversationID, err := strconv.ParseInt(c.Param("conversation_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Query("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	if err := mc.messageService.DeleteConversationForMe(conversationID, userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "会话已删除"})
+}
+
 // SearchPrivateMessages 搜索私聊消息
 func (mc *MessageController) SearchPrivateMessages(c *gin.Context) {
 	// 从路径参数获取对方用户ID
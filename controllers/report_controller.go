@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"campus-canvas-chat/middleware"
+	"campus-canvas-chat/models"
+	"campus-canvas-chat/services"
+	"campus-canvas-chat/websocket"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportController struct {
+	reportService *services.ReportService
+	webSocketHub  *websocket.Hub
+}
+
+func NewReportController(webSocketHub *websocket.Hub) *ReportController {
+	return &ReportController{
+		reportService: services.NewReportService(),
+		webSocketHub:  webSocketHub,
+	}
+}
+
+// reportResolutionRequest 处理举报的请求参数
+type reportResolutionRequest struct {
+	Status          string `json:"status" binding:"required,oneof=ACTIONED DISMISSED"`
+	Action          string `json:"action" binding:"omitempty,oneof=DELETE_MESSAGE MUTE BAN DEACTIVATE_ROOM DISABLE_USER"`
+	DurationMinutes int    `json:"durationMinutes" binding:"min=0"` // 禁言或封禁时长，0表示永久
+	Note            string `json:"note" binding:"max=500"`
+}
+
+// CreateReport 提交举报
+func (ctrl *ReportController) CreateReport(c *gin.Context) {
+	var req struct {
+		ReporterID int64  `json:"reporterId" binding:"required"`
+		TargetType string `json:"targetType" binding:"required,oneof=GROUP_MESSAGE PRIVATE_MESSAGE USER CHATROOM"`
+		TargetID   int64  `json:"targetId" binding:"min=0"`
+		ChatRoomID *int64 `json:"chatRoomId"`
+		Category   string `json:"category" binding:"required,oneof=SPAM HARASSMENT PORNOGRAPHY VIOLENCE ILLEGAL OTHER"`
+		Reason     string `json:"reason" binding:"max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := &models.Report{
+		ReporterID: req.ReporterID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		ChatRoomID: req.ChatRoomID,
+		Category:   req.Category,
+		Reason:     req.Reason,
+	}
+
+	if err := ctrl.reportService.CreateReport(report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "举报已提交",
+		"data":    report,
+	})
+}
+
+// GetUserReports 获取用户提交的举报
+func (ctrl *ReportController) GetUserReports(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	page, pageSize := parseReportPage(c)
+	reports, total, err := ctrl.reportService.GetUserReports(userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondReports(c, reports, total, page, pageSize)
+}
+
+// GetRoomReports 获取聊天室内的举报队列
+func (ctrl *ReportController) GetRoomReports(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	operatorID, err := strconv.ParseInt(c.Query("operatorId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的操作者ID"})
+		return
+	}
+
+	page, pageSize := parseReportPage(c)
+	reports, total, err := ctrl.reportService.GetRoomReports(roomID, operatorID, c.DefaultQuery("status", "OPEN"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondReports(c, reports, total, page, pageSize)
+}
+
+// HandleRoomReport 房主或管理员处理聊天室内的举报
+func (ctrl *ReportController) HandleRoomReport(c *gin.Context) {
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.ParseInt(roomIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的聊天室ID"})
+		return
+	}
+
+	reportIDStr := c.Param("report_id")
+	reportID, err := strconv.ParseInt(reportIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的举报ID"})
+		return
+	}
+
+	var req struct {
+		OperatorID int64 `json:"operatorId" binding:"required"`
+		reportResolutionRequest
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resolution, ok := buildReportResolution(c, req.reportResolutionRequest)
+	if !ok {
+		return
+	}
+
+	report, err := ctrl.reportService.HandleRoomReport(roomID, reportID, req.OperatorID, resolution)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctrl.notifyReportAction(report, resolution)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "举报处理成功",
+		"data":    report,
+	})
+}
+
+// GetReports 获取全平台举报队列
+func (ctrl *ReportController) GetReports(c *gin.Context) {
+	page, pageSize := parseReportPage(c)
+	reports, total, err := ctrl.reportService.GetReports(
+		c.DefaultQuery("status", "OPEN"),
+		c.Query("target_type"),
+		c.Query("category"),
+		page, pageSize,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondReports(c, reports, total, page, pageSize)
+}
+
+// HandleReport 平台管理员处理举报
+func (ctrl *ReportController) HandleReport(c *gin.Context) {
+	reportIDStr := c.Param("report_id")
+	reportID, err := strconv.ParseInt(reportIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的举报ID"})
+		return
+	}
+
+	var req reportResolutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resolution, ok := buildReportResolution(c, req)
+	if !ok {
+		return
+	}
+
+	report, err := ctrl.reportService.HandleReport(middleware.GetAdmin(c), reportID, resolution)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctrl.notifyReportAction(report, resolution)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "举报处理成功",
+		"data":    report,
+	})
+}
+
+// buildReportResolution 校验处理参数，处理时必须指定动作，驳回时不能指定动作
+func buildReportResolution(c *gin.Context, req reportResolutionRequest) (services.ReportResolution, bool) {
+	if req.Status == "ACTIONED" && req.Action == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "处理举报时必须指定处理动作"})
+		return services.ReportResolution{}, false
+	}
+	if req.Status == "DISMISSED" && req.Action != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "驳回举报时不能指定处理动作"})
+		return services.ReportResolution{}, false
+	}
+
+	return services.ReportResolution{
+		Status:   req.Status,
+		Action:   req.Action,
+		Duration: time.Duration(req.DurationMinutes) * time.Minute,
+		Note:     req.Note,
+	}, true
+}
+
+// notifyReportAction 通知受处理动作影响的在线用户
+func (ctrl *ReportController) notifyReportAction(report *models.Report, resolution services.ReportResolution) {
+	if report.Status != "ACTIONED" {
+		return
+	}
+
+	// 禁用用户不一定关联聊天室，断开该用户的所有连接
+	if report.Resolution == "DISABLE_USER" {
+		ctrl.webSocketHub.DisconnectUser(*report.TargetUserID, "账号已被禁用")
+		return
+	}
+	if report.ChatRoomID == nil {
+		return
+	}
+
+	switch report.Resolution {
+	case "MUTE":
+		var mutedUntil *time.Time
+		if resolution.Duration > 0 {
+			until := report.HandledAt.Add(resolution.Duration)
+			mutedUntil = &until
+		}
+		messageData, _ := json.Marshal(map[string]interface{}{
+			"type":       "mute_status",
+			"roomId":     *report.ChatRoomID,
+			"muted":      true,
+			"mutedUntil": mutedUntil,
+			"reason":     resolution.Note,
+		})
+		ctrl.webSocketHub.SendToUser(*report.TargetUserID, messageData)
+
+	case "BAN":
+		ctrl.webSocketHub.DisconnectUserFromRoom(*report.ChatRoomID, *report.TargetUserID, resolution.Note)
+
+	case "DEACTIVATE_ROOM":
+		messageData, _ := json.Marshal(map[string]interface{}{
+			"type":   "room_deactivated",
+			"roomId": *report.ChatRoomID,
+			"reason": resolution.Note,
+		})
+		ctrl.webSocketHub.BroadcastToRoom(*report.ChatRoomID, messageData)
+	}
+}
+
+// parseReportPage 解析分页参数
+func parseReportPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	return page, pageSize
+}
+
+// respondReports 返回分页的举报列表
+func respondReports(c *gin.Context, reports []models.Report, total int64, page, pageSize int) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"reports":    reports,
+			"total":      total,
+			"page":       page,
+			"page_size":  pageSize,
+			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"campus-canvas-chat/services"
+	"campus-canvas-chat/websocket"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserPrivacyController struct {
+	privacyService *services.UserPrivacyService
+	webSocketHub   *websocket.Hub
+}
+
+func NewUserPrivacyController(webSocketHub *websocket.Hub) *UserPrivacyController {
+	return &UserPrivacyController{
+		privacyService: services.NewUserPrivacyService(),
+		webSocketHub:   webSocketHub,
+	}
+}
+
+// BlockUser 拉黑用户
+func (ctrl *UserPrivacyController) BlockUser(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	var req struct {
+		BlockedUserID int64 `json:"blockedUserId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.privacyService.BlockUser(userID, req.BlockedUserID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctrl.webSocketHub.RefreshHiddenSenders(userID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "拉黑成功"})
+}
+
+// UnblockUser 解除拉黑
+func (ctrl *UserPrivacyController) UnblockUser(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	blockedIDStr := c.Param("blocked_id")
+	blockedID, err := strconv.ParseInt(blockedIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	if err := ctrl.privacyService.UnblockUser(userID, blockedID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctrl.webSocketHub.RefreshHiddenSenders(userID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "已解除拉黑"})
+}
+
+// GetBlockedUsers 获取拉黑列表
+func (ctrl *UserPrivacyController) GetBlockedUsers(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	blocks, err := ctrl.privacyService.GetBlockedUsers(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": blocks})
+}
+
+// GetChatSetting 获取聊天隐私设置
+func (ctrl *UserPrivacyController) GetChatSetting(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	setting, err := ctrl.privacyService.GetChatSetting(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": setting})
+}
+
+// UpdateChatSetting 更新聊天隐私设置
+func (ctrl *UserPrivacyController) UpdateChatSetting(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	var req struct {
+		DMPolicy            string `json:"dmPolicy" binding:"omitempty,oneof=EVERYONE SHARED_ROOM"`
+		HideBlockedInGroups *bool  `json:"hideBlockedInGroups"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.DMPolicy != "" {
+		updates["dm_policy"] = req.DMPolicy
+	}
+	if req.HideBlockedInGroups != nil {
+		updates["hide_blocked_in_groups"] = *req.HideBlockedInGroups
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的字段"})
+		return
+	}
+
+	setting, err := ctrl.privacyService.UpdateChatSetting(userID, updates)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctrl.webSocketHub.RefreshHiddenSenders(userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "设置更新成功",
+		"data":    setting,
+	})
+}
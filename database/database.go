@@ -24,12 +24,24 @@ func InitDatabase(cfg *config.Config) error {
 		return err
 	}
 
+	// 清理重复数据，保证唯一索引可以创建
+	err = DeduplicateData()
+	if err != nil {
+		return err
+	}
+
 	// 自动迁移表结构
 	err = AutoMigrate()
 	if err != nil {
 		return err
 	}
 
+	// 补全历史数据
+	err = BackfillData()
+	if err != nil {
+		return err
+	}
+
 	log.Println("数据库连接成功")
 	return nil
 }
@@ -40,16 +52,125 @@ func AutoMigrate() error {
 		&models.User{},
 		&models.ChatRoom{},
 		&models.ChatRoomMember{},
+		&models.ChatRoomJoinRequest{},
+		&models.ChatRoomInvite{},
+		&models.ChatRoomBan{},
 		&models.Message{},
 		&models.Admin{},
 		&models.CheckIn{},
 		&models.CheckInTask{},
+		&models.CheckInMakeup{},
+		&models.CheckInAttachment{},
+		&models.CheckInLike{},
+		&models.CheckInComment{},
+		&models.UserBadge{},
 		&models.Conversation{},
+		&models.ConversationParticipant{},
 		&models.PrivateMessage{},
 		&models.ConversationUnreadCount{},
+		&models.AuditLog{},
+		&models.SensitiveWord{},
+		&models.ContentFilterHit{},
+		&models.Report{},
+		&models.UserBlock{},
+		&models.UserChatSetting{},
 	)
 }
 
+// DeduplicateData 合并唯一索引新增前产生的重复数据（需在AutoMigrate之前执行，可重复执行）
+func DeduplicateData() error {
+	migrator := DB.Migrator()
+
+	// 同一对用户的重复一对一会话合并到最早创建的会话
+	if migrator.HasTable(&models.Conversation{}) {
+		duplicates := `SELECT user1_id, user2_id, MIN(id) AS keep_id,
+				MAX(last_message_id) AS last_message_id, MAX(last_message_time) AS last_message_time
+			FROM conversation WHERE user1_id IS NOT NULL AND user2_id IS NOT NULL
+			GROUP BY user1_id, user2_id HAVING COUNT(*) > 1`
+
+		statements := []string{
+			`UPDATE conversation AS c JOIN (` + duplicates + `) AS d ON c.id = d.keep_id
+			SET c.last_message_id = d.last_message_id, c.last_message_time = d.last_message_time`,
+			`UPDATE conversation_unread_count AS u
+			JOIN conversation AS c ON u.conversation_id = c.id
+			JOIN (` + duplicates + `) AS d ON c.user1_id = d.user1_id AND c.user2_id = d.user2_id AND c.id <> d.keep_id
+			SET u.conversation_id = d.keep_id`,
+		}
+		if migrator.HasColumn(&models.PrivateMessage{}, "conversation_id") {
+			statements = append(statements, `UPDATE private_message AS pm
+			JOIN conversation AS c ON pm.conversation_id = c.id
+			JOIN (`+duplicates+`) AS d ON c.user1_id = d.user1_id AND c.user2_id = d.user2_id AND c.id <> d.keep_id
+			SET pm.conversation_id = d.keep_id`)
+		}
+		if migrator.HasTable(&models.ConversationParticipant{}) {
+			statements = append(statements, `DELETE cp FROM conversation_participant AS cp
+			JOIN conversation AS c ON cp.conversation_id = c.id
+			JOIN (`+duplicates+`) AS d ON c.user1_id = d.user1_id AND c.user2_id = d.user2_id AND c.id <> d.keep_id`)
+		}
+		statements = append(statements, `DELETE c FROM conversation AS c
+			JOIN (`+duplicates+`) AS d ON c.user1_id = d.user1_id AND c.user2_id = d.user2_id AND c.id <> d.keep_id`)
+
+		for _, statement := range statements {
+			if err := DB.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	// 同一会话同一用户的重复未读计数合并为一条
+	if migrator.HasTable(&models.ConversationUnreadCount{}) {
+		duplicates := `SELECT conversation_id, user_id, MIN(id) AS keep_id, SUM(unread_count) AS unread_count
+			FROM conversation_unread_count GROUP BY conversation_id, user_id HAVING COUNT(*) > 1`
+
+		if err := DB.Exec(`UPDATE conversation_unread_count AS u JOIN (` + duplicates + `) AS d ON u.id = d.keep_id
+			SET u.unread_count = d.unread_count`).Error; err != nil {
+			return err
+		}
+		if err := DB.Exec(`DELETE u FROM conversation_unread_count AS u
+			JOIN (` + duplicates + `) AS d ON u.conversation_id = d.conversation_id AND u.user_id = d.user_id AND u.id <> d.keep_id`).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BackfillData 为新增字段补全历史数据（可重复执行）
+func BackfillData() error {
+	// 审核状态字段新增前已通过审核的聊天室
+	if err := DB.Model(&models.ChatRoom{}).
+		Where("is_approved = ? AND review_status = ?", true, "PENDING").
+		Update("review_status", "APPROVED").Error; err != nil {
+		return err
+	}
+
+	// 打卡任务字段新增前的打卡记录：聊天室只有一个每日任务时归入该任务
+	if err := DB.Exec(`UPDATE IGNORE checkin AS ci
+		JOIN (SELECT chat_room_id, MIN(id) AS task_id FROM checkin_task
+			GROUP BY chat_room_id HAVING COUNT(*) = 1 AND MIN(cycle) = 'DAILY') AS t ON t.chat_room_id = ci.chat_room_id
+		SET ci.task_id = t.task_id, ci.period_start = ci.check_date
+		WHERE ci.task_id IS NULL`).Error; err != nil {
+		return err
+	}
+
+	// 为参与者表新增前的一对一会话补充参与者
+	for _, column := range []string{"user1_id", "user2_id"} {
+		if err := DB.Exec(`INSERT IGNORE INTO conversation_participant (conversation_id, user_id, role, joined_at)
+			SELECT id, ` + column + `, 'MEMBER', created_at FROM conversation
+			WHERE type = 'DIRECT' AND ` + column + ` IS NOT NULL`).Error; err != nil {
+			return err
+		}
+	}
+
+	// 为会话ID字段新增前的私聊消息关联会话
+	return DB.Exec(`UPDATE private_message AS pm
+		JOIN conversation AS c ON c.type = 'DIRECT'
+			AND c.user1_id = LEAST(pm.sender_id, pm.receiver_id)
+			AND c.user2_id = GREATEST(pm.sender_id, pm.receiver_id)
+		SET pm.conversation_id = c.id
+		WHERE pm.conversation_id IS NULL`).Error
+}
+
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
 	return DB
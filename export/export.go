@@ -0,0 +1,220 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// RowWriter 表格导出器，逐行写入，不在内存中保留已写入的行
+type RowWriter interface {
+	// WriteRow 写入一行，整数和浮点数写为数字，时间写为日期，其余按字符串写入
+	WriteRow(values ...interface{}) error
+	// Close 写完剩余内容，不关闭底层的io.Writer
+	Close() error
+}
+
+// NewWriter 按格式（csv或xlsx）创建导出器
+func NewWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case "csv":
+		return NewCSVWriter(w)
+	case "xlsx":
+		return NewXLSXWriter(w)
+	default:
+		return nil, errors.New("不支持的导出格式")
+	}
+}
+
+// ContentType 导出格式对应的Content-Type
+func ContentType(format string) string {
+	if format == "xlsx" {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// formatValue 把单元格的值转为文本
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format("2006-01-02")
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format("2006-01-02")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "是"
+		}
+		return "否"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// escapeFormula 以=、+、-、@、制表符或回车开头的文本会被电子表格当作公式执行，前面加'按文本显示
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// CSVWriter CSV导出器，开头写入UTF-8 BOM，避免Excel打开中文乱码
+type CSVWriter struct {
+	writer *csv.Writer
+}
+
+// NewCSVWriter 创建CSV导出器
+func NewCSVWriter(w io.Writer) (*CSVWriter, error) {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return nil, err
+	}
+	return &CSVWriter{writer: csv.NewWriter(w)}, nil
+}
+
+// WriteRow 写入一行，用户输入的字符串转义公式前缀，数字保持原样
+func (cw *CSVWriter) WriteRow(values ...interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = formatValue(value)
+		if _, ok := value.(string); ok {
+			record[i] = escapeFormula(record[i])
+		}
+	}
+	return cw.writer.Write(record)
+}
+
+// Close 刷新缓冲区
+func (cw *CSVWriter) Close() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+// XLSX文件中除工作表外的固定部分
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// XLSXWriter 单工作表的XLSX导出器，工作表放在zip的最后一个文件中逐行写出
+type XLSXWriter struct {
+	zipWriter *zip.Writer
+	sheet     *bufio.Writer
+	rowNum    int
+}
+
+// NewXLSXWriter 创建XLSX导出器，先写入固定部分再打开工作表
+func NewXLSXWriter(w io.Writer) (*XLSXWriter, error) {
+	zipWriter := zip.NewWriter(w)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := zipWriter.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zipWriter.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err := sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+
+	return &XLSXWriter{zipWriter: zipWriter, sheet: sheet}, nil
+}
+
+// WriteRow 写入一行，字符串使用内联字符串，不需要共享字符串表
+// 内联字符串始终按文本显示，不会被当作公式执行，因此不需要转义
+func (xw *XLSXWriter) WriteRow(values ...interface{}) error {
+	xw.rowNum++
+	fmt.Fprintf(xw.sheet, `<row r="%d">`, xw.rowNum)
+	for i, value := range values {
+		ref := columnName(i) + strconv.Itoa(xw.rowNum)
+		switch v := value.(type) {
+		case int, int32, int64:
+			fmt.Fprintf(xw.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case float64:
+			fmt.Fprintf(xw.sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			fmt.Fprintf(xw.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(xw.sheet, []byte(sanitizeXMLText(formatValue(value)))); err != nil {
+				return err
+			}
+			xw.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	_, err := xw.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Close 结束工作表并写出zip目录
+func (xw *XLSXWriter) Close() error {
+	if _, err := xw.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zipWriter.Close()
+}
+
+// sanitizeXMLText 去掉XML 1.0不允许出现的字符（制表符、换行、回车以外的控制字符，U+FFFE、U+FFFF及无效的UTF-8），
+// 否则Excel会认为文件已损坏
+func sanitizeXMLText(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < 0x20, r == utf8.RuneError, r == 0xFFFE, r == 0xFFFF:
+			return -1
+		default:
+			return r
+		}
+	}, text)
+}
+
+// columnName 列下标转为Excel列名（0→A，26→AA）
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
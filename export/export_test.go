@@ -0,0 +1,242 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"空字符串", "", ""},
+		{"普通文本", "你好", "你好"},
+		{"等号开头", "=1+1", "'=1+1"},
+		{"加号开头", "+86", "'+86"},
+		{"减号开头", "-1", "'-1"},
+		{"@开头", "@SUM(A1)", "'@SUM(A1)"},
+		{"制表符开头", "\tx", "'\tx"},
+		{"回车开头", "\rx", "'\rx"},
+		{"中间的等号不转义", "a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeFormula(tt.text); got != tt.want {
+				t.Errorf("escapeFormula(%q) = %q，期望 %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{1, "B"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, tt := range tests {
+		if got := columnName(tt.index); got != tt.want {
+			t.Errorf("columnName(%d) = %q，期望 %q", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeXMLText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"普通文本", "你好 abc", "你好 abc"},
+		{"保留制表符和换行", "a\tb\nc\rd", "a\tb\nc\rd"},
+		{"去掉控制字符", "a\x00b\x08c\x0Bd\x0Ce\x1Ff", "abcdef"},
+		{"去掉非字符", "a\uFFFEb\uFFFFc", "abc"},
+		{"去掉无效的UTF-8", "a\xffb", "ab"},
+		{"保留表情", "😀", "😀"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeXMLText(tt.text); got != tt.want {
+				t.Errorf("sanitizeXMLText(%q) = %q，期望 %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf)
+	if err != nil {
+		t.Fatalf("创建CSV导出器失败: %v", err)
+	}
+
+	date := time.Date(2024, 3, 10, 8, 0, 0, 0, time.Local)
+	rows := [][]interface{}{
+		{"用户名", "打卡次数", "日期"},
+		{"=HYPERLINK(\"x\")", int64(12), date},
+		{"a,b", -3, 1.5},
+		{"多行\n内容", true, (*time.Time)(nil)},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row...); err != nil {
+			t.Fatalf("写入行失败: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("关闭CSV导出器失败: %v", err)
+	}
+
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("\xEF\xBB\xBF")) {
+		t.Fatalf("CSV开头缺少UTF-8 BOM")
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data[3:])).ReadAll()
+	if err != nil {
+		t.Fatalf("解析CSV失败: %v", err)
+	}
+	want := [][]string{
+		{"用户名", "打卡次数", "日期"},
+		{"'=HYPERLINK(\"x\")", "12", "2024-03-10"},
+		{"a,b", "-3", "1.5"},
+		{"多行\n内容", "是", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV内容 = %q，期望 %q", records, want)
+	}
+}
+
+// xlsxCell 工作表中的一个单元格
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline string `xml:"is>t"`
+}
+
+// readXLSXSheet 读取XLSX中的工作表并按行返回单元格
+func readXLSXSheet(t *testing.T, data []byte) [][]xlsxCell {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("打开XLSX失败: %v", err)
+	}
+
+	names := make([]string, 0, len(reader.File))
+	var sheet []byte
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("打开%s失败: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("读取%s失败: %v", f.Name, err)
+		}
+
+		// 每个部分都必须是合法的XML
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s不是合法的XML: %v", f.Name, err)
+			}
+		}
+
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			sheet = content
+		}
+	}
+
+	wantNames := []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("XLSX文件列表 = %v，期望 %v", names, wantNames)
+	}
+
+	var worksheet struct {
+		Rows []struct {
+			Ref   string     `xml:"r,attr"`
+			Cells []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &worksheet); err != nil {
+		t.Fatalf("解析工作表失败: %v", err)
+	}
+
+	rows := make([][]xlsxCell, len(worksheet.Rows))
+	for i, row := range worksheet.Rows {
+		rows[i] = row.Cells
+	}
+	return rows
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewXLSXWriter(&buf)
+	if err != nil {
+		t.Fatalf("创建XLSX导出器失败: %v", err)
+	}
+
+	date := time.Date(2024, 3, 10, 8, 0, 0, 0, time.Local)
+	rows := [][]interface{}{
+		{"用户名", "打卡次数", "时长"},
+		{"=1+1 <b>&", int64(12), 1.5},
+		{"控制\x00字符\x1F", 7, date},
+		{"换行\n保留", int32(-2), false},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row...); err != nil {
+			t.Fatalf("写入行失败: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("关闭XLSX导出器失败: %v", err)
+	}
+
+	want := [][]xlsxCell{
+		{{Ref: "A1", Type: "inlineStr", Inline: "用户名"}, {Ref: "B1", Type: "inlineStr", Inline: "打卡次数"}, {Ref: "C1", Type: "inlineStr", Inline: "时长"}},
+		{{Ref: "A2", Type: "inlineStr", Inline: "=1+1 <b>&"}, {Ref: "B2", Value: "12"}, {Ref: "C2", Value: "1.5"}},
+		{{Ref: "A3", Type: "inlineStr", Inline: "控制字符"}, {Ref: "B3", Value: "7"}, {Ref: "C3", Type: "inlineStr", Inline: "2024-03-10"}},
+		{{Ref: "A4", Type: "inlineStr", Inline: "换行\n保留"}, {Ref: "B4", Value: "-2"}, {Ref: "C4", Type: "inlineStr", Inline: "否"}},
+	}
+	if got := readXLSXSheet(t, buf.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("工作表内容 = %+v，期望 %+v", got, want)
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	for _, format := range []string{"csv", "xlsx"} {
+		if _, err := NewWriter(format, io.Discard); err != nil {
+			t.Errorf("NewWriter(%q) 失败: %v", format, err)
+		}
+	}
+
+	if _, err := NewWriter("pdf", io.Discard); err == nil || !strings.Contains(err.Error(), "不支持") {
+		t.Errorf("NewWriter(\"pdf\") 错误 = %v，期望不支持的导出格式", err)
+	}
+}
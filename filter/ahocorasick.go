@@ -0,0 +1,132 @@
+package filter
+
+import (
+	"unicode"
+)
+
+// Match 一次敏感词命中
+type Match struct {
+	Start int // 命中词在文本中的起始位置（按字符计）
+	End   int // 命中词在文本中的结束位置（不含，按字符计）
+	Index int // 命中词在构建词表中的下标
+}
+
+// node Trie树节点
+type node struct {
+	children map[rune]int
+	fail     int
+	outputs  []int // 以该节点结尾的词下标
+}
+
+// Matcher 基于Aho-Corasick自动机的多模式匹配器（忽略大小写），构建后只读，可并发使用
+type Matcher struct {
+	nodes   []node
+	lengths []int // 每个词的字符长度
+}
+
+// NewMatcher 根据词表构建匹配器，空词会被忽略
+func NewMatcher(words []string) *Matcher {
+	m := &Matcher{
+		nodes:   []node{{children: make(map[rune]int)}},
+		lengths: make([]int, len(words)),
+	}
+
+	// 构建Trie树
+	for index, word := range words {
+		current := 0
+		length := 0
+		for _, r := range word {
+			r = unicode.ToLower(r)
+			next, exists := m.nodes[current].children[r]
+			if !exists {
+				m.nodes = append(m.nodes, node{children: make(map[rune]int)})
+				next = len(m.nodes) - 1
+				m.nodes[current].children[r] = next
+			}
+			current = next
+			length++
+		}
+		if length == 0 {
+			continue
+		}
+		m.lengths[index] = length
+		m.nodes[current].outputs = append(m.nodes[current].outputs, index)
+	}
+
+	// 按层序构建失败指针
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for r, child := range m.nodes[current].children {
+			fail := m.nodes[current].fail
+			for fail != 0 {
+				if _, exists := m.nodes[fail].children[r]; exists {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if next, exists := m.nodes[fail].children[r]; exists && next != child {
+				m.nodes[child].fail = next
+			}
+			// 继承失败节点的输出，保证后缀词也能命中
+			m.nodes[child].outputs = append(m.nodes[child].outputs, m.nodes[m.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+
+	return m
+}
+
+// FindAll 查找文本中所有命中的词
+func (m *Matcher) FindAll(text string) []Match {
+	if m == nil || len(m.nodes) <= 1 {
+		return nil
+	}
+
+	var matches []Match
+	current := 0
+	position := 0
+	for _, r := range text {
+		r = unicode.ToLower(r)
+		for current != 0 {
+			if _, exists := m.nodes[current].children[r]; exists {
+				break
+			}
+			current = m.nodes[current].fail
+		}
+		if next, exists := m.nodes[current].children[r]; exists {
+			current = next
+		}
+
+		position++
+		for _, index := range m.nodes[current].outputs {
+			matches = append(matches, Match{
+				Start: position - m.lengths[index],
+				End:   position,
+				Index: index,
+			})
+		}
+	}
+
+	return matches
+}
+
+// Mask 将命中词所在位置的字符替换为*，返回替换后的文本
+func Mask(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+
+	runes := []rune(text)
+	for _, match := range matches {
+		for i := match.Start; i < match.End; i++ {
+			runes[i] = '*'
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// sortMatches 按位置和词下标排序，便于比较
+func sortMatches(matches []Match) []Match {
+	sorted := append([]Match(nil), matches...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End < b.End
+		}
+		return a.Index < b.Index
+	})
+	return sorted
+}
+
+func TestFindAll(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		text  string
+		want  []Match
+	}{
+		{"空文本", []string{"abc"}, "", nil},
+		{"空词表", nil, "abc", nil},
+		{"空词被忽略", []string{""}, "abc", nil},
+		{"未命中", []string{"abc"}, "abd", nil},
+		{"重叠命中", []string{"he", "she", "his", "hers"}, "ushers", []Match{
+			{Start: 1, End: 4, Index: 1},
+			{Start: 2, End: 4, Index: 0},
+			{Start: 2, End: 6, Index: 3},
+		}},
+		{"嵌套命中", []string{"敏感", "敏感词", "感词"}, "这是敏感词", []Match{
+			{Start: 2, End: 4, Index: 0},
+			{Start: 2, End: 5, Index: 1},
+			{Start: 3, End: 5, Index: 2},
+		}},
+		{"后缀词通过失败指针输出", []string{"abcd", "bcd", "cd"}, "xabcd", []Match{
+			{Start: 1, End: 5, Index: 0},
+			{Start: 2, End: 5, Index: 1},
+			{Start: 3, End: 5, Index: 2},
+		}},
+		{"失配后沿失败指针继续匹配", []string{"abd", "bc"}, "abc", []Match{
+			{Start: 1, End: 3, Index: 1},
+		}},
+		{"同一词连续重叠", []string{"aa"}, "aaa", []Match{
+			{Start: 0, End: 2, Index: 0},
+			{Start: 1, End: 3, Index: 0},
+		}},
+		{"重复的词各自命中", []string{"x", "x"}, "x", []Match{
+			{Start: 0, End: 1, Index: 0},
+			{Start: 0, End: 1, Index: 1},
+		}},
+		{"忽略大小写", []string{"Spam"}, "SPAM and spam", []Match{
+			{Start: 0, End: 4, Index: 0},
+			{Start: 9, End: 13, Index: 0},
+		}},
+		{"中文按字符计位置", []string{"傻瓜"}, "你是傻瓜吗，傻瓜", []Match{
+			{Start: 2, End: 4, Index: 0},
+			{Start: 6, End: 8, Index: 0},
+		}},
+		{"中英文和表情混合", []string{"坏蛋", "bad"}, "😀bad坏蛋😀", []Match{
+			{Start: 1, End: 4, Index: 1},
+			{Start: 4, End: 6, Index: 0},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sortMatches(NewMatcher(tt.words).FindAll(tt.text))
+			want := sortMatches(tt.want)
+			if len(got) == 0 && len(want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("FindAll(%q) = %v，期望 %v", tt.text, got, want)
+			}
+		})
+	}
+}
+
+func TestFindAllNilMatcher(t *testing.T) {
+	var matcher *Matcher
+	if got := matcher.FindAll("abc"); got != nil {
+		t.Errorf("nil匹配器FindAll = %v，期望 nil", got)
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		matches []Match
+		want    string
+	}{
+		{"无命中原样返回", "你好", nil, "你好"},
+		{"空文本", "", nil, ""},
+		{"中文打码", "你是傻瓜吗", []Match{{Start: 2, End: 4}}, "你是**吗"},
+		{"重叠区域只替换一次", "ushers", []Match{{Start: 1, End: 4}, {Start: 2, End: 6}}, "u*****"},
+		{"表情按一个字符替换", "a😀b", []Match{{Start: 1, End: 2}}, "a*b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mask(tt.text, tt.matches); got != tt.want {
+				t.Errorf("Mask(%q) = %q，期望 %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindAllThenMask(t *testing.T) {
+	tests := []struct {
+		words []string
+		text  string
+		want  string
+	}{
+		{[]string{"spam", "敏感"}, "SPAM和敏感词", "****和**词"},
+		{[]string{"he", "she", "hers"}, "ushers", "u*****"},
+		{[]string{"傻瓜"}, "没有命中", "没有命中"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Mask(tt.text, NewMatcher(tt.words).FindAll(tt.text)); got != tt.want {
+				t.Errorf("Mask(%q) = %q，期望 %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"campus-canvas-chat/database"
 	"campus-canvas-chat/redis"
 	"campus-canvas-chat/routes"
+	"campus-canvas-chat/scheduler"
+	"campus-canvas-chat/services"
 	"campus-canvas-chat/websocket"
 	"log"
+	"time"
+	_ "time/tzdata" // 内置时区数据，部署环境缺少时区库时也能加载校园时区
 )
 
 func main() {
@@ -23,10 +27,23 @@ func main() {
 		log.Fatalf("Redis初始化失败: %v", err)
 	}
 
+	// 加载敏感词并监听变更
+	if err := services.LoadSensitiveWords(); err != nil {
+		log.Fatalf("敏感词加载失败: %v", err)
+	}
+	go services.StartFilterReloadListener()
+
 	// 创建WebSocket Hub
 	hub := websocket.NewHub()
 	go hub.Run()
 
+	// 启动定时任务（多实例部署时只有一个实例执行）
+	reminderService := services.NewCheckInReminderService(hub)
+	jobs := scheduler.New()
+	jobs.Every("checkin_reminder", time.Minute, reminderService.SendDueReminders)
+	jobs.Every("checkin_daily_summary", time.Minute, reminderService.PostDailySummaries)
+	go jobs.Start()
+
 	// 设置路由
 	r := routes.SetupRoutes(hub)
 
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"campus-canvas-chat/models"
+	"campus-canvas-chat/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminContextKey 当前管理员在gin.Context中的键名
+const AdminContextKey = "admin"
+
+// RequireAdmin 校验请求者（查询参数adminId）是否为启用状态的平台管理员
+func RequireAdmin() gin.HandlerFunc {
+	adminService := services.NewAdminService()
+
+	return func(c *gin.Context) {
+		adminUserID, err := strconv.ParseInt(c.Query("adminId"), 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "缺少管理员ID参数"})
+			return
+		}
+
+		admin, err := adminService.GetActiveAdmin(adminUserID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(AdminContextKey, admin)
+		c.Next()
+	}
+}
+
+// RequireAdminRole 校验当前管理员的角色，需在RequireAdmin之后使用
+func RequireAdminRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin := GetAdmin(c)
+		if admin == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "无管理员权限"})
+			return
+		}
+
+		for _, role := range roles {
+			if admin.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "管理员权限不足"})
+	}
+}
+
+// GetAdmin 获取当前请求的管理员
+func GetAdmin(c *gin.Context) *models.Admin {
+	value, exists := c.Get(AdminContextKey)
+	if !exists {
+		return nil
+	}
+	admin, _ := value.(*models.Admin)
+	return admin
+}
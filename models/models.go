@@ -20,18 +20,27 @@ type User struct {
 
 // ChatRoom 聊天室表
 type ChatRoom struct {
-	ID          int64          `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name        string         `gorm:"size:100;not null" json:"name"`
-	Description string         `gorm:"size:1000" json:"description"`
-	Category    string         `gorm:"size:50;not null" json:"category"` // 技术、艺术、运动等
-	CreatorID   int64          `gorm:"not null" json:"creatorId"`
-	Creator     User           `gorm:"foreignKey:CreatorID" json:"creator"`
-	MaxMembers  int            `gorm:"default:100" json:"maxMembers"`
-	IsActive    bool           `gorm:"default:true" json:"isActive"`
-	IsApproved  bool           `gorm:"default:false" json:"isApproved"` // 需要审核
-	CreatedAt   time.Time      `json:"createdAt"`
-	UpdatedAt   time.Time      `json:"updatedAt"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name             string         `gorm:"size:100;not null" json:"name"`
+	Description      string         `gorm:"size:1000" json:"description"`
+	Category         string         `gorm:"size:50;not null" json:"category"` // 技术、艺术、运动等
+	CreatorID        int64          `gorm:"not null" json:"creatorId"`
+	Creator          User           `gorm:"foreignKey:CreatorID" json:"creator"`
+	MaxMembers       int            `gorm:"default:100" json:"maxMembers"`
+	IsActive         bool           `gorm:"default:true" json:"isActive"`
+	IsApproved       bool           `gorm:"default:false" json:"isApproved"`                                        // 需要审核
+	JoinPolicy       string         `gorm:"type:enum('OPEN','APPROVAL','INVITE');default:'OPEN'" json:"joinPolicy"` // 加入方式：直接加入、需审批、仅邀请
+	ReviewStatus     string         `gorm:"type:enum('PENDING','APPROVED','REJECTED');default:'PENDING';index" json:"reviewStatus"`
+	ReviewReason     string         `gorm:"size:500" json:"reviewReason"`
+	ReviewerID       *int64         `json:"reviewerId"`
+	ReviewedAt       *time.Time     `json:"reviewedAt"`
+	DeactivateReason string         `gorm:"size:500" json:"deactivateReason"` // 平台强制停用原因
+	AllMuted         bool           `gorm:"default:false" json:"allMuted"`    // 全员禁言（房主和管理员除外）
+	SlowModeSeconds  int            `gorm:"default:0" json:"slowModeSeconds"` // 慢速模式：普通成员两次发言的最小间隔秒数，0表示关闭
+	Timezone         string         `gorm:"size:64" json:"timezone"`          // 打卡日期使用的时区（IANA名称），为空时使用校园默认时区
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 关联
 	Members  []ChatRoomMember `gorm:"foreignKey:ChatRoomID" json:"members,omitempty"`
@@ -41,39 +50,91 @@ type ChatRoom struct {
 
 // ChatRoomMember 聊天室成员表
 type ChatRoomMember struct {
-	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	ChatRoomID int64     `gorm:"not null" json:"chatRoomId"`
-	UserID     int64     `gorm:"not null" json:"userId"`
-	Role       string    `gorm:"type:enum('MEMBER','ADMIN','OWNER');default:'MEMBER'" json:"role"`
-	IsMuted    bool      `gorm:"default:false" json:"isMuted"`
-	JoinedAt   time.Time `json:"joinedAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
+	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ChatRoomID int64      `gorm:"not null" json:"chatRoomId"`
+	UserID     int64      `gorm:"not null" json:"userId"`
+	Role       string     `gorm:"type:enum('MEMBER','ADMIN','OWNER');default:'MEMBER'" json:"role"`
+	IsMuted    bool       `gorm:"default:false" json:"isMuted"`
+	MutedUntil *time.Time `json:"mutedUntil"` // 禁言到期时间，为空表示永久禁言
+	MuteReason string     `gorm:"size:200" json:"muteReason"`
+	JoinedAt   time.Time  `json:"joinedAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+
+	CheckInRemindersOff bool `gorm:"default:false" json:"checkInRemindersOff"` // 关闭该聊天室的打卡提醒
 
 	// 关联
-	ChatRoom ChatRoom `gorm:"foreignKey:ChatRoomID" json:"-"` // Prevent ChatRoom from being serialized to avoid circular dependency
-	User     User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ChatRoom ChatRoom    `gorm:"foreignKey:ChatRoomID" json:"-"` // Prevent ChatRoom from being serialized to avoid circular dependency
+	User     User        `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Badges   []UserBadge `gorm:"-" json:"badges,omitempty"` // 成员在该聊天室获得的徽章，仅在聊天室详情中填充
+}
+
+// ChatRoomJoinRequest 入群申请表
+type ChatRoomJoinRequest struct {
+	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ChatRoomID int64      `gorm:"not null;index" json:"chatRoomId"`
+	UserID     int64      `gorm:"not null;index" json:"userId"`
+	Message    string     `gorm:"size:200" json:"message"` // 申请附言
+	Status     string     `gorm:"type:enum('PENDING','APPROVED','REJECTED');default:'PENDING';index" json:"status"`
+	ReviewerID *int64     `json:"reviewerId"`
+	ReviewedAt *time.Time `json:"reviewedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+
+	// 关联
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// ChatRoomInvite 聊天室邀请码表
+type ChatRoomInvite struct {
+	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ChatRoomID int64      `gorm:"not null;index" json:"chatRoomId"`
+	Code       string     `gorm:"size:32;uniqueIndex;not null" json:"code"`
+	CreatorID  int64      `gorm:"not null" json:"creatorId"`
+	MaxUses    int        `gorm:"default:0" json:"maxUses"` // 0表示不限次数
+	UsedCount  int        `gorm:"default:0" json:"usedCount"`
+	ExpiresAt  *time.Time `json:"expiresAt"` // 为空表示永不过期
+	IsActive   bool       `gorm:"default:true" json:"isActive"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// ChatRoomBan 聊天室封禁表（被封禁的用户不能重新加入）
+type ChatRoomBan struct {
+	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ChatRoomID int64      `gorm:"not null;uniqueIndex:idx_chatroom_ban_room_user" json:"chatRoomId"`
+	UserID     int64      `gorm:"not null;uniqueIndex:idx_chatroom_ban_room_user" json:"userId"`
+	OperatorID int64      `gorm:"not null" json:"operatorId"`
+	Reason     string     `gorm:"size:200" json:"reason"`
+	ExpiresAt  *time.Time `json:"expiresAt"` // 为空表示永久封禁
+	CreatedAt  time.Time  `json:"createdAt"`
+
+	// 关联
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
 // Message 群聊消息表（持久化存储）
 type Message struct {
-	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	ChatRoomID int64     `gorm:"not null;index" json:"chatRoomId"`
-	UserID     int64     `gorm:"not null;index" json:"userId"`
-	Content    string    `gorm:"type:text;not null" json:"content"`
-	CreatedAt  time.Time `gorm:"index" json:"createdAt"`
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ChatRoomID  int64     `gorm:"not null;index" json:"chatRoomId"`
+	UserID      int64     `gorm:"not null;index;uniqueIndex:idx_message_client_msg" json:"userId"`
+	ClientMsgID *string   `gorm:"size:64;uniqueIndex:idx_message_client_msg" json:"clientMsgId,omitempty"` // 客户端生成的消息ID，用于重试去重
+	Content     string    `gorm:"type:text;not null" json:"content"`
+	CreatedAt   time.Time `gorm:"index" json:"createdAt"`
 
 	// 群聊消息持久化存储，不维护已读未读状态
 }
 
 // PrivateMessage 私聊消息表（持久化存储）
 type PrivateMessage struct {
-	ID         int64          `gorm:"primaryKey;autoIncrement" json:"id"`
-	SenderID   int64          `gorm:"not null;index" json:"senderId"`
-	ReceiverID int64          `gorm:"not null;index" json:"receiverId"`
-	Content    string         `gorm:"type:text;not null" json:"content"`
-	CreatedAt  time.Time      `gorm:"index" json:"createdAt"`
-	UpdatedAt  time.Time      `json:"updatedAt"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	SenderID       int64          `gorm:"not null;index;uniqueIndex:idx_private_message_client_msg" json:"senderId"`
+	ReceiverID     int64          `gorm:"not null;index" json:"receiverId"` // 多人会话消息为0
+	ConversationID *int64         `gorm:"index" json:"conversationId"`
+	ClientMsgID    *string        `gorm:"size:64;uniqueIndex:idx_private_message_client_msg" json:"clientMsgId,omitempty"` // 客户端生成的消息ID，用于重试去重
+	Content        string         `gorm:"type:text;not null" json:"content"`
+	CreatedAt      time.Time      `gorm:"index" json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 关联字段已移除，减少数据传输冗余
 	// 如需用户信息，请通过 SenderID 和 ReceiverID 单独查询
@@ -82,8 +143,8 @@ type PrivateMessage struct {
 // ConversationUnreadCount 会话未读消息计数表
 type ConversationUnreadCount struct {
 	ID             int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	ConversationID int64     `gorm:"not null;index" json:"conversationId"`
-	UserID         int64     `gorm:"not null;index" json:"userId"`
+	ConversationID int64     `gorm:"not null;uniqueIndex:idx_conversation_unread_user" json:"conversationId"`
+	UserID         int64     `gorm:"not null;uniqueIndex:idx_conversation_unread_user;index" json:"userId"`
 	UnreadCount    int64     `gorm:"default:0" json:"unreadCount"`
 	CreatedAt      time.Time `json:"createdAt"`
 	UpdatedAt      time.Time `json:"updatedAt"`
@@ -108,17 +169,60 @@ type Admin struct {
 
 // CheckIn 打卡表
 type CheckIn struct {
-	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	ChatRoomID int64     `gorm:"not null;index" json:"chatRoomId"`
-	UserID     int64     `gorm:"not null;index" json:"userId"`
-	Content    string    `gorm:"size:500" json:"content"`
-	CheckDate  time.Time `gorm:"type:date;not null;index" json:"checkDate"`
-	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
+	ID          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ChatRoomID  int64      `gorm:"not null;index" json:"chatRoomId"`
+	TaskID      *int64     `gorm:"uniqueIndex:idx_checkin_task_period" json:"taskId"` // 所属打卡任务，任务功能上线前的记录可能为空
+	UserID      int64      `gorm:"not null;index;uniqueIndex:idx_checkin_task_period" json:"userId"`
+	Content     string     `gorm:"size:500" json:"content"`
+	CheckDate   time.Time  `gorm:"type:date;not null;index" json:"checkDate"`
+	PeriodStart *time.Time `gorm:"type:date;uniqueIndex:idx_checkin_task_period" json:"periodStart"` // 所属打卡周期的第一天，每个任务每周期只能打卡一次
+	IsMakeup    bool       `gorm:"default:false" json:"isMakeup"`                                    // 是否为补卡
+
+	DurationMinutes int    `gorm:"default:0" json:"durationMinutes"` // 学习时长（分钟）
+	Location        string `gorm:"size:100" json:"location"`         // 地点标签
+	LikeCount       int    `gorm:"default:0" json:"likeCount"`
+	CommentCount    int    `gorm:"default:0" json:"commentCount"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 
 	// 关联
-	ChatRoom ChatRoom `gorm:"foreignKey:ChatRoomID" json:"-"` // Prevent ChatRoom from being serialized to avoid circular dependency
-	User     User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ChatRoom    ChatRoom            `gorm:"foreignKey:ChatRoomID" json:"-"` // Prevent ChatRoom from being serialized to avoid circular dependency
+	User        User                `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Attachments []CheckInAttachment `gorm:"foreignKey:CheckInID" json:"attachments,omitempty"`
+}
+
+// CheckInAttachment 打卡附件表（笔记照片等）
+type CheckInAttachment struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CheckInID int64     `gorm:"not null;index" json:"checkInId"`
+	URL       string    `gorm:"size:500;not null" json:"url"`
+	SortOrder int       `gorm:"default:0" json:"sortOrder"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CheckInLike 打卡点赞表
+type CheckInLike struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CheckInID int64     `gorm:"not null;uniqueIndex:idx_checkin_like_user" json:"checkInId"`
+	UserID    int64     `gorm:"not null;uniqueIndex:idx_checkin_like_user;index" json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// 关联
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// CheckInComment 打卡评论表
+type CheckInComment struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CheckInID int64     `gorm:"not null;index" json:"checkInId"`
+	UserID    int64     `gorm:"not null;index" json:"userId"`
+	Content   string    `gorm:"size:500;not null" json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// 关联
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
 // CheckInTask 打卡任务表
@@ -131,18 +235,62 @@ type CheckInTask struct {
 	IsActive    bool       `gorm:"default:true" json:"isActive"`
 	StartDate   time.Time  `gorm:"type:date;not null" json:"startDate"`
 	EndDate     *time.Time `gorm:"type:date" json:"endDate"`
+
+	// 补卡设置
+	MakeupWindowDays       int  `gorm:"default:0" json:"makeupWindowDays"`           // 可补卡的最早天数（今天之前N天内），0表示不允许补卡
+	MakeupLimit            int  `gorm:"default:0" json:"makeupLimit"`                // 每个周期最多申请补卡次数，0表示不限制
+	MakeupRequiresApproval bool `gorm:"default:false" json:"makeupRequiresApproval"` // 补卡是否需要房主或管理员审批
+
+	ReminderTime string `gorm:"size:5" json:"reminderTime"` // 周期最后一天的提醒时间（HH:MM，聊天室时区），为空表示不提醒
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// 关联
+	ChatRoom ChatRoom `gorm:"foreignKey:ChatRoomID" json:"-"` // Prevent ChatRoom from being serialized to avoid circular dependency
+}
+
+// CheckInMakeup 补卡申请表
+type CheckInMakeup struct {
+	ID          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ChatRoomID  int64      `gorm:"not null;index" json:"chatRoomId"`
+	TaskID      int64      `gorm:"not null;index" json:"taskId"`
+	UserID      int64      `gorm:"not null;index" json:"userId"`
+	CheckDate   time.Time  `gorm:"type:date;not null" json:"checkDate"`   // 补卡的日期
+	PeriodStart time.Time  `gorm:"type:date;not null" json:"periodStart"` // 补卡日期所属打卡周期的第一天
+	Content     string     `gorm:"size:500" json:"content"`
+	Reason      string     `gorm:"size:500" json:"reason"`
+	Status      string     `gorm:"type:enum('PENDING','APPROVED','REJECTED');default:'PENDING';index" json:"status"`
+	ReviewerID  *int64     `json:"reviewerId"`
+	ReviewedAt  *time.Time `json:"reviewedAt"`
+	ReviewNote  string     `gorm:"size:500" json:"reviewNote"`
+	CheckInID   *int64     `json:"checkInId"` // 通过后生成的打卡记录
 	CreatedAt   time.Time  `json:"createdAt"`
 	UpdatedAt   time.Time  `json:"updatedAt"`
 
 	// 关联
-	ChatRoom ChatRoom `gorm:"foreignKey:ChatRoomID" json:"-"` // Prevent ChatRoom from being serialized to avoid circular dependency
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// UserBadge 用户在聊天室获得的打卡成就徽章，每种徽章每个聊天室只获得一次
+type UserBadge struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ChatRoomID int64     `gorm:"not null;uniqueIndex:idx_user_badge" json:"chatRoomId"`
+	UserID     int64     `gorm:"not null;uniqueIndex:idx_user_badge;index" json:"userId"`
+	BadgeCode  string    `gorm:"size:50;not null;uniqueIndex:idx_user_badge" json:"badgeCode"`
+	BadgeName  string    `gorm:"size:50;not null" json:"badgeName"`
+	CheckInID  *int64    `json:"checkInId"` // 触发获得徽章的打卡记录
+	AwardedAt  time.Time `json:"awardedAt"`
 }
 
-// Conversation 会话表（用于私聊会话管理）
+// Conversation 会话表（用于私聊会话管理，支持一对一和多人会话）
 type Conversation struct {
 	ID              int64      `gorm:"primaryKey;autoIncrement" json:"id"`
-	User1ID         int64      `gorm:"not null;index" json:"user1Id"`
-	User2ID         int64      `gorm:"not null;index" json:"user2Id"`
+	Type            string     `gorm:"type:enum('DIRECT','GROUP');default:'DIRECT';index" json:"type"`
+	Name            string     `gorm:"size:100" json:"name"`                                   // 多人会话名称
+	CreatorID       *int64     `json:"creatorId"`                                              // 多人会话创建者
+	User1ID         *int64     `gorm:"uniqueIndex:idx_conversation_pair" json:"user1Id"`       // 一对一会话中ID较小的用户，多人会话为空
+	User2ID         *int64     `gorm:"uniqueIndex:idx_conversation_pair;index" json:"user2Id"` // 一对一会话中ID较大的用户，多人会话为空
 	LastMessageID   *int64     `gorm:"index" json:"lastMessageId"`
 	LastMessageTime *time.Time `json:"lastMessageTime"`
 	CreatedAt       time.Time  `json:"createdAt"`
@@ -151,7 +299,106 @@ type Conversation struct {
 	// 关联字段已移除，减少数据传输冗余
 	// 如需用户信息，请通过 User1ID 和 User2ID 单独查询
 	// 如需最后一条消息详情，请通过 LastMessageID 单独查询
-	LastMessage *PrivateMessage `gorm:"foreignKey:LastMessageID" json:"lastMessage,omitempty"`
+	LastMessage  *PrivateMessage           `gorm:"foreignKey:LastMessageID" json:"lastMessage,omitempty"`
+	Participants []ConversationParticipant `gorm:"foreignKey:ConversationID" json:"participants,omitempty"`
+}
+
+// ConversationParticipant 会话参与者表
+type ConversationParticipant struct {
+	ID             int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConversationID int64     `gorm:"not null;uniqueIndex:idx_conversation_participant" json:"conversationId"`
+	UserID         int64     `gorm:"not null;uniqueIndex:idx_conversation_participant;index" json:"userId"`
+	Role           string    `gorm:"type:enum('OWNER','MEMBER');default:'MEMBER'" json:"role"` // 多人会话的创建者为OWNER
+	JoinedAt       time.Time `json:"joinedAt"`
+
+	// 仅对该参与者生效的会话设置
+	IsMuted    bool       `gorm:"default:false" json:"isMuted"`  // 免打扰，未读数不计入总数
+	IsPinned   bool       `gorm:"default:false" json:"isPinned"` // 置顶
+	PinnedAt   *time.Time `json:"pinnedAt"`
+	IsArchived bool       `gorm:"default:false" json:"isArchived"` // 归档，不在默认会话列表中显示
+	ClearedAt  *time.Time `json:"clearedAt"`                       // 删除会话的时间，此前的消息对该参与者不可见
+}
+
+// AuditLog 审计日志表（仅追加，记录特权操作）
+type AuditLog struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ActorID    int64     `gorm:"not null;index" json:"actorId"`
+	Action     string    `gorm:"size:50;not null;index" json:"action"`
+	TargetType string    `gorm:"size:30" json:"targetType"` // USER、CHATROOM、CHECKIN_TASK等
+	TargetID   int64     `gorm:"index" json:"targetId"`
+	ChatRoomID *int64    `gorm:"index" json:"chatRoomId"`
+	Before     string    `gorm:"type:text" json:"before"` // 变更前的值（JSON）
+	After      string    `gorm:"type:text" json:"after"`  // 变更后的值（JSON）
+	CreatedAt  time.Time `gorm:"index" json:"createdAt"`
+}
+
+// SensitiveWord 敏感词表
+type SensitiveWord struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Word      string    `gorm:"size:100;uniqueIndex;not null" json:"word"`
+	Action    string    `gorm:"type:enum('REJECT','MASK','FLAG');default:'MASK'" json:"action"` // 命中后的处理：拒绝、打码、标记待审
+	IsActive  bool      `gorm:"default:true" json:"isActive"`
+	CreatorID int64     `gorm:"not null" json:"creatorId"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ContentFilterHit 敏感词命中记录表
+type ContentFilterHit struct {
+	ID           int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	WordID       int64      `gorm:"not null;index" json:"wordId"`
+	Word         string     `gorm:"size:100;not null" json:"word"`
+	Action       string     `gorm:"size:20;not null" json:"action"`
+	Source       string     `gorm:"size:30;not null;index" json:"source"` // GROUP_MESSAGE、PRIVATE_MESSAGE、CHECKIN、CHATROOM
+	UserID       int64      `gorm:"not null;index" json:"userId"`
+	ChatRoomID   *int64     `gorm:"index" json:"chatRoomId"`
+	Content      string     `gorm:"type:text" json:"content"` // 原始内容
+	ReviewStatus string     `gorm:"type:enum('PENDING','REVIEWED');default:'PENDING';index" json:"reviewStatus"`
+	ReviewerID   *int64     `json:"reviewerId"`
+	ReviewedAt   *time.Time `json:"reviewedAt"`
+	CreatedAt    time.Time  `gorm:"index" json:"createdAt"`
+}
+
+// Report 用户举报表
+type Report struct {
+	ID              int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ReporterID      int64      `gorm:"not null;index" json:"reporterId"`
+	TargetType      string     `gorm:"type:enum('GROUP_MESSAGE','PRIVATE_MESSAGE','USER','CHATROOM');not null;index:idx_report_target" json:"targetType"`
+	TargetID        int64      `gorm:"not null;index:idx_report_target" json:"targetId"` // 消息ID、用户ID或聊天室ID，未落库的群聊消息为0
+	TargetUserID    *int64     `gorm:"index" json:"targetUserId"`                        // 被举报的用户（消息发送者或被举报用户）
+	ChatRoomID      *int64     `gorm:"index" json:"chatRoomId"`                          // 举报内容所在的聊天室
+	Category        string     `gorm:"type:enum('SPAM','HARASSMENT','PORNOGRAPHY','VIOLENCE','ILLEGAL','OTHER');not null" json:"category"`
+	Reason          string     `gorm:"size:500" json:"reason"`
+	ContentSnapshot string     `gorm:"type:text" json:"contentSnapshot"` // 举报时的消息内容
+	Status          string     `gorm:"type:enum('OPEN','ACTIONED','DISMISSED');default:'OPEN';index" json:"status"`
+	Resolution      string     `gorm:"size:30" json:"resolution"` // 处理动作：DELETE_MESSAGE、MUTE、BAN、DEACTIVATE_ROOM、DISABLE_USER
+	ResolutionNote  string     `gorm:"size:500" json:"resolutionNote"`
+	HandlerID       *int64     `json:"handlerId"`
+	HandledAt       *time.Time `json:"handledAt"`
+	CreatedAt       time.Time  `gorm:"index" json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+
+	// 关联
+	Reporter User `gorm:"foreignKey:ReporterID" json:"reporter,omitempty"`
+}
+
+// UserBlock 用户拉黑表
+type UserBlock struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	BlockerID int64     `gorm:"not null;uniqueIndex:idx_user_block_pair" json:"blockerId"`
+	BlockedID int64     `gorm:"not null;uniqueIndex:idx_user_block_pair;index" json:"blockedId"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// 关联
+	Blocked User `gorm:"foreignKey:BlockedID" json:"blocked,omitempty"`
+}
+
+// UserChatSetting 用户聊天隐私设置表（无记录时使用默认值）
+type UserChatSetting struct {
+	UserID              int64     `gorm:"primaryKey" json:"userId"`
+	DMPolicy            string    `gorm:"column:dm_policy;type:enum('EVERYONE','SHARED_ROOM');default:'EVERYONE'" json:"dmPolicy"` // 私信接收范围：所有人、仅同一聊天室成员
+	HideBlockedInGroups bool      `gorm:"default:false" json:"hideBlockedInGroups"`                                                // 在群聊中隐藏已拉黑用户的消息
+	UpdatedAt           time.Time `json:"updatedAt"`
 }
 
 // TableName 设置表名
@@ -167,6 +414,18 @@ func (ChatRoomMember) TableName() string {
 	return "chatroom_member"
 }
 
+func (ChatRoomJoinRequest) TableName() string {
+	return "chatroom_join_request"
+}
+
+func (ChatRoomInvite) TableName() string {
+	return "chatroom_invite"
+}
+
+func (ChatRoomBan) TableName() string {
+	return "chatroom_ban"
+}
+
 func (Message) TableName() string {
 	return "message"
 }
@@ -194,3 +453,51 @@ func (CheckInTask) TableName() string {
 func (ConversationUnreadCount) TableName() string {
 	return "conversation_unread_count"
 }
+
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
+
+func (SensitiveWord) TableName() string {
+	return "sensitive_word"
+}
+
+func (ContentFilterHit) TableName() string {
+	return "content_filter_hit"
+}
+
+func (Report) TableName() string {
+	return "report"
+}
+
+func (UserBlock) TableName() string {
+	return "user_block"
+}
+
+func (UserChatSetting) TableName() string {
+	return "user_chat_setting"
+}
+
+func (ConversationParticipant) TableName() string {
+	return "conversation_participant"
+}
+
+func (CheckInMakeup) TableName() string {
+	return "checkin_makeup"
+}
+
+func (CheckInAttachment) TableName() string {
+	return "checkin_attachment"
+}
+
+func (CheckInLike) TableName() string {
+	return "checkin_like"
+}
+
+func (CheckInComment) TableName() string {
+	return "checkin_comment"
+}
+
+func (UserBadge) TableName() string {
+	return "user_badge"
+}
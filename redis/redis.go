@@ -55,6 +55,26 @@ func IsUserOnline(userID int64) bool {
 	return result.Val() > 0
 }
 
+// GetUsersOnline 批量检查用户是否在线
+func GetUsersOnline(userIDs []int64) map[int64]bool {
+	online := make(map[int64]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return online
+	}
+
+	pipe := Client.Pipeline()
+	results := make([]*redis.IntCmd, len(userIDs))
+	for i, userID := range userIDs {
+		results[i] = pipe.Exists(ctx, fmt.Sprintf("user:online:%d", userID))
+	}
+	pipe.Exec(ctx)
+
+	for i, userID := range userIDs {
+		online[userID] = results[i].Val() > 0
+	}
+	return online
+}
+
 // AddUserToRoom 将用户添加到房间
 func AddUserToRoom(roomID, userID int64) error {
 	key := fmt.Sprintf("room:users:%d", roomID)
@@ -84,14 +104,27 @@ func CacheMessage(userID int64, messageData string) error {
 	return err
 }
 
-// GetOfflineMessages 获取离线消息
+// GetOfflineMessages 获取并清空离线消息，读取和删除在同一个MULTI事务中执行，期间新缓存的消息不会丢失
 func GetOfflineMessages(userID int64) ([]string, error) {
 	key := fmt.Sprintf("offline:messages:%d", userID)
-	messages, err := Client.LRange(ctx, key, 0, -1).Result()
+	var messages *redis.StringSliceCmd
+	_, err := Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		messages = pipe.LRange(ctx, key, 0, -1)
+		pipe.Del(ctx, key)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	// 获取后清空离线消息
-	Client.Del(ctx, key)
-	return messages, nil
-}
\ No newline at end of file
+	return messages.Val(), nil
+}
+
+// Publish 向指定频道发布消息
+func Publish(channel, message string) error {
+	return Client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe 订阅指定频道
+func Subscribe(channels ...string) *redis.PubSub {
+	return Client.Subscribe(ctx, channels...)
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"campus-canvas-chat/controllers"
+	"campus-canvas-chat/middleware"
 	"campus-canvas-chat/services"
 	"campus-canvas-chat/websocket"
 
@@ -24,9 +25,15 @@ func SetupRoutes(hub *websocket.Hub) *gin.Engine {
 	messageService := services.NewMessageService()
 
 	// 初始化控制器
-	chatRoomController := controllers.NewChatRoomController()
+	chatRoomController := controllers.NewChatRoomController(hub)
 	messageController := controllers.NewMessageController(messageService, hub)
-	checkInController := controllers.NewCheckInController()
+	checkInController := controllers.NewCheckInController(hub)
+	adminController := controllers.NewAdminController(hub)
+	auditLogController := controllers.NewAuditLogController()
+	contentFilterController := controllers.NewContentFilterController()
+	reportController := controllers.NewReportController(hub)
+	userPrivacyController := controllers.NewUserPrivacyController(hub)
+	conversationController := controllers.NewConversationController(hub)
 
 	// API版本分组
 	v1 := r.Group("/campus-canvas/api")
@@ -45,16 +52,89 @@ func SetupRoutes(hub *websocket.Hub) *gin.Engine {
 			chatRooms.POST("/:id/leave", chatRoomController.LeaveChatRoom)          // 离开聊天室
 			chatRooms.PUT("/:id/members/role", chatRoomController.UpdateMemberRole) // 更新成员角色
 			chatRooms.PUT("/:id/members/mute", chatRoomController.MuteMember)       // 禁言/解禁成员
+			chatRooms.PUT("/:id/mute-all", chatRoomController.SetAllMuted)          // 开启/关闭全员禁言
+			chatRooms.PUT("/:id/slow-mode", chatRoomController.SetSlowMode)         // 设置慢速模式
+			chatRooms.PUT("/:id/timezone", chatRoomController.SetTimezone)          // 设置打卡时区
 			chatRooms.DELETE("/:id/members/kick", chatRoomController.KickMember)    // 踢出成员
+			chatRooms.PUT("/:id/owner", chatRoomController.TransferOwnership)       // 转让房主
+
+			// 封禁管理
+			chatRooms.POST("/:id/bans", chatRoomController.BanMember)            // 封禁用户
+			chatRooms.GET("/:id/bans", chatRoomController.GetBans)               // 获取封禁列表
+			chatRooms.DELETE("/:id/bans/:user_id", chatRoomController.UnbanUser) // 解除封禁
+
+			// 加入方式与邀请
+			chatRooms.PUT("/:id/join-policy", chatRoomController.SetJoinPolicy)                   // 设置加入方式
+			chatRooms.GET("/:id/join-requests", chatRoomController.GetJoinRequests)               // 获取入群申请列表
+			chatRooms.PUT("/:id/join-requests/:request_id", chatRoomController.ReviewJoinRequest) // 审批入群申请
+			chatRooms.POST("/:id/invites", chatRoomController.CreateInvite)                       // 创建邀请码
+			chatRooms.GET("/:id/invites", chatRoomController.GetInvites)                          // 获取邀请码列表
+			chatRooms.DELETE("/:id/invites/:invite_id", chatRoomController.RevokeInvite)          // 作废邀请码
+
+			// 举报处理
+			chatRooms.GET("/:id/reports", reportController.GetRoomReports)              // 获取聊天室举报队列
+			chatRooms.PUT("/:id/reports/:report_id", reportController.HandleRoomReport) // 处理聊天室举报
+
+			// 审计日志
+			chatRooms.GET("/:id/audit-logs", auditLogController.GetRoomAuditLogs) // 获取聊天室审计日志（房主）
 
 			// 管理员功能
-			chatRooms.PUT("/:id/approve", chatRoomController.ApproveChatRoom) // 审核聊天室
+			chatRooms.PUT("/:id/approve", middleware.RequireAdmin(), adminController.ReviewChatRoom) // 审核聊天室
+		}
+
+		// 平台管理后台路由
+		admin := v1.Group("/admin", middleware.RequireAdmin())
+		{
+			// 聊天室审核与管理
+			admin.GET("/chatrooms/pending", adminController.GetPendingChatRooms)       // 获取待审核聊天室
+			admin.PUT("/chatrooms/:id/review", adminController.ReviewChatRoom)         // 审核聊天室
+			admin.PUT("/chatrooms/:id/deactivate", adminController.DeactivateChatRoom) // 强制停用聊天室
+
+			// 用户管理
+			admin.PUT("/users/:user_id/status", adminController.SetUserStatus) // 启用/禁用用户
+
+			// 审计日志
+			admin.GET("/audit-logs", auditLogController.GetAuditLogs) // 获取全平台审计日志
+
+			// 举报管理
+			admin.GET("/reports", reportController.GetReports)              // 获取全平台举报队列
+			admin.PUT("/reports/:report_id", reportController.HandleReport) // 处理举报
+
+			// 敏感词管理
+			admin.GET("/sensitive-words", contentFilterController.GetSensitiveWords)               // 获取敏感词列表
+			admin.POST("/sensitive-words", contentFilterController.CreateSensitiveWord)            // 添加敏感词
+			admin.PUT("/sensitive-words/:word_id", contentFilterController.UpdateSensitiveWord)    // 修改敏感词
+			admin.DELETE("/sensitive-words/:word_id", contentFilterController.DeleteSensitiveWord) // 删除敏感词
+			admin.GET("/filter-hits", contentFilterController.GetFilterHits)                       // 获取敏感词命中记录
+			admin.PUT("/filter-hits/:hit_id/review", contentFilterController.ReviewFilterHit)      // 审核命中记录
+
+			// 管理员账户管理（仅超级管理员）
+			moderators := admin.Group("/moderators", middleware.RequireAdminRole("SUPER_ADMIN"))
+			{
+				moderators.GET("", adminController.GetAdmins)             // 获取管理员列表
+				moderators.POST("", adminController.CreateAdmin)          // 添加管理员
+				moderators.PUT("/:admin_id", adminController.UpdateAdmin) // 修改管理员
+			}
 		}
 
 		// 用户相关路由
 		users := v1.Group("/users")
 		{
 			users.GET("/:user_id/chatrooms", chatRoomController.GetUserChatRooms) // 获取用户加入的聊天室
+			users.GET("/:user_id/reports", reportController.GetUserReports)       // 获取用户提交的举报
+
+			// 拉黑与隐私设置
+			users.POST("/:user_id/blocks", userPrivacyController.BlockUser)                 // 拉黑用户
+			users.GET("/:user_id/blocks", userPrivacyController.GetBlockedUsers)            // 获取拉黑列表
+			users.DELETE("/:user_id/blocks/:blocked_id", userPrivacyController.UnblockUser) // 解除拉黑
+			users.GET("/:user_id/chat-settings", userPrivacyController.GetChatSetting)      // 获取聊天隐私设置
+			users.PUT("/:user_id/chat-settings", userPrivacyController.UpdateChatSetting)   // 更新聊天隐私设置
+		}
+
+		// 举报路由
+		reports := v1.Group("/reports")
+		{
+			reports.POST("", reportController.CreateReport) // 提交举报
 		}
 
 		// 群聊消息路由
@@ -70,12 +150,26 @@ func SetupRoutes(hub *websocket.Hub) *gin.Engine {
 			privateMessages.POST("/send", messageController.SendPrivateMessage)
 			privateMessages.GET("/with/:user_id", messageController.GetPrivateMessages)
 			privateMessages.GET("/conversations", messageController.GetConversations)
+			privateMessages.PUT("/conversations/:conversation_id/settings", messageController.UpdateConversationSettings)
+			privateMessages.DELETE("/conversations/:conversation_id", messageController.DeleteConversationForMe)
 			privateMessages.GET("/unread/count", messageController.GetUserTotalUnreadCount)
 			privateMessages.POST("/clear-unread", messageController.ClearConversationUnreadCount)
 			privateMessages.GET("/search/:user_id", messageController.SearchPrivateMessages)
 			privateMessages.DELETE("/:message_id", messageController.DeletePrivateMessage)
 		}
 
+		// 多人会话路由
+		conversations := v1.Group("/conversations")
+		{
+			conversations.POST("", conversationController.CreateGroupConversation)                                    // 创建多人会话
+			conversations.GET("/:conversation_id", conversationController.GetConversation)                            // 获取会话详情
+			conversations.PUT("/:conversation_id", conversationController.RenameConversation)                         // 修改会话名称
+			conversations.POST("/:conversation_id/participants", conversationController.AddParticipants)              // 邀请成员
+			conversations.DELETE("/:conversation_id/participants/:user_id", conversationController.RemoveParticipant) // 移出成员/退出会话
+			conversations.POST("/:conversation_id/messages", conversationController.SendConversationMessage)          // 发送会话消息
+			conversations.GET("/:conversation_id/messages", conversationController.GetConversationMessages)           // 获取会话消息
+		}
+
 		// 打卡相关路由
 		checkIns := v1.Group("/checkins")
 		{
@@ -89,13 +183,52 @@ func SetupRoutes(hub *websocket.Hub) *gin.Engine {
 			}
 
 			// 打卡记录
-			checkIns.POST("", checkInController.SubmitCheckIn)                      // 提交打卡
-			checkIns.GET("/room/:room_id", checkInController.GetCheckInRecords)     // 获取打卡记录
-			checkIns.GET("/room/:room_id/stats", checkInController.GetCheckInStats) // 获取打卡统计
+			checkIns.POST("", checkInController.SubmitCheckIn)                                  // 提交打卡
+			checkIns.GET("/room/:room_id", checkInController.GetCheckInRecords)                 // 获取打卡记录
+			checkIns.GET("/room/:room_id/stats", checkInController.GetCheckInStats)             // 获取打卡统计
+			checkIns.GET("/room/:room_id/leaderboard", checkInController.GetCheckInLeaderboard) // 获取打卡排行榜
+			checkIns.GET("/room/:room_id/export", checkInController.ExportCheckInRecords)       // 导出打卡记录（房主和管理员）
+			checkIns.GET("/room/:room_id/stats/export", checkInController.ExportCheckInStats)   // 导出打卡统计（房主和管理员）
+
+			// 补卡
+			makeups := checkIns.Group("/makeups")
+			{
+				makeups.POST("", checkInController.SubmitMakeup)                              // 提交补卡申请
+				makeups.GET("/room/:room_id", checkInController.GetRoomMakeups)               // 获取聊天室补卡申请（房主和管理员）
+				makeups.GET("/room/:room_id/user/:user_id", checkInController.GetUserMakeups) // 获取用户的补卡申请
+				makeups.PUT("/:id/review", checkInController.ReviewMakeup)                    // 审批补卡申请
+			}
+
+			// 打卡动态、点赞和评论
+			checkIns.GET("/room/:room_id/feed", checkInController.GetCheckInFeed)            // 获取聊天室打卡动态
+			checkIns.POST("/:id/likes", checkInController.LikeCheckIn)                       // 点赞打卡
+			checkIns.DELETE("/:id/likes/:user_id", checkInController.UnlikeCheckIn)          // 取消点赞
+			checkIns.GET("/:id/likes", checkInController.GetCheckInLikes)                    // 获取点赞列表
+			checkIns.POST("/:id/comments", checkInController.AddCheckInComment)              // 评论打卡
+			checkIns.GET("/:id/comments", checkInController.GetCheckInComments)              // 获取打卡评论
+			checkIns.DELETE("/comments/:comment_id", checkInController.DeleteCheckInComment) // 删除打卡评论
+
+			// 打卡分析
+			analytics := checkIns.Group("/analytics")
+			{
+				analytics.GET("/room/:room_id/user/:user_id/heatmap", checkInController.GetUserHeatmap)       // 用户打卡日历热力图
+				analytics.GET("/room/:room_id/user/:user_id/completion", checkInController.GetUserCompletion) // 用户各任务完成率
+				analytics.GET("/room/:room_id/completion", checkInController.GetRoomCompletion)               // 聊天室各任务完成率
+				analytics.GET("/room/:room_id/missed", checkInController.GetMissedToday)                      // 当前周期未打卡成员（房主和管理员）
+				analytics.GET("/room/:room_id/trend", checkInController.GetParticipationTrend)                // 每日参与趋势
+			}
+
+			// 成就徽章
+			badges := checkIns.Group("/badges")
+			{
+				badges.GET("", checkInController.GetBadgeRules)                             // 获取徽章定义
+				badges.GET("/room/:room_id/user/:user_id", checkInController.GetUserBadges) // 获取用户在聊天室的徽章
+			}
 
 			// 用户打卡历史
 			checkIns.GET("/room/:room_id/user/:user_id/history", checkInController.GetUserCheckInHistory) // 获取用户打卡历史
 			checkIns.GET("/room/:room_id/user/:user_id/today", checkInController.GetTodayCheckInStatus)   // 获取今天打卡状态
+			checkIns.PUT("/room/:room_id/user/:user_id/reminder", checkInController.SetReminderEnabled)   // 开启/关闭打卡提醒
 		}
 	}
 
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	campusredis "campus-canvas-chat/redis"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	leaderKey     = "scheduler:leader"
+	leaderTTL     = 30 * time.Second
+	checkInterval = 10 * time.Second
+)
+
+// renewScript 仅当锁仍由当前实例持有时续期
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Job 定时任务，每个间隔周期执行一次
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(now time.Time) error
+}
+
+// Scheduler 定时任务调度器，多实例部署时通过Redis锁选出一个实例执行任务
+type Scheduler struct {
+	jobs       []Job
+	instanceID string
+	lastSlots  map[string]int64
+}
+
+// New 创建调度器
+func New() *Scheduler {
+	randomBytes := make([]byte, 8)
+	rand.Read(randomBytes)
+
+	return &Scheduler{
+		instanceID: hex.EncodeToString(randomBytes),
+		lastSlots:  make(map[string]int64),
+	}
+}
+
+// Every 注册按固定间隔执行的任务
+func (s *Scheduler) Every(name string, interval time.Duration, run func(now time.Time) error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start 启动调度循环（阻塞，需在goroutine中调用）
+func (s *Scheduler) Start() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if !s.isLeader() {
+			continue
+		}
+
+		for _, job := range s.jobs {
+			s.runJob(job, now)
+		}
+	}
+}
+
+// isLeader 获取或续期调度锁，返回当前实例是否为执行任务的实例
+func (s *Scheduler) isLeader() bool {
+	ctx := context.Background()
+	client := campusredis.GetClient()
+
+	acquired, err := client.SetNX(ctx, leaderKey, s.instanceID, leaderTTL).Result()
+	if err != nil {
+		return false
+	}
+	if acquired {
+		log.Printf("调度器实例 %s 成为执行实例", s.instanceID)
+		return true
+	}
+
+	renewed, err := renewScript.Run(ctx, client, []string{leaderKey}, s.instanceID, leaderTTL.Milliseconds()).Int()
+	return err == nil && renewed == 1
+}
+
+// runJob 在任务进入新的间隔周期时执行一次，并在Redis中标记，避免执行实例切换时重复执行
+func (s *Scheduler) runJob(job Job, now time.Time) {
+	slot := now.Truncate(job.Interval).Unix()
+	if s.lastSlots[job.Name] == slot {
+		return
+	}
+
+	key := fmt.Sprintf("scheduler:job:%s:%d", job.Name, slot)
+	claimed, err := campusredis.GetClient().SetNX(context.Background(), key, s.instanceID, job.Interval+leaderTTL).Result()
+	if err != nil {
+		return
+	}
+	s.lastSlots[job.Name] = slot
+	if !claimed {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("定时任务 %s 执行异常: %v", job.Name, r)
+		}
+	}()
+
+	if err := job.Run(now); err != nil {
+		log.Printf("定时任务 %s 执行失败: %v", job.Name, err)
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BadgeRule 成就徽章规则，每次打卡后检查是否达成
+type BadgeRule struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	check func(ctx *achievementContext) (bool, error)
+}
+
+// badgeRules 所有成就徽章规则，按展示顺序排列
+var badgeRules = []BadgeRule{
+	{Code: "FIRST_CHECKIN", Name: "初次打卡", Description: "在聊天室完成第一次打卡", check: func(ctx *achievementContext) (bool, error) {
+		count, err := ctx.totalCheckIns()
+		return count >= 1, err
+	}},
+	{Code: "CHECKINS_30", Name: "打卡30次", Description: "在聊天室累计打卡30次", check: func(ctx *achievementContext) (bool, error) {
+		count, err := ctx.totalCheckIns()
+		return count >= 30, err
+	}},
+	{Code: "CHECKINS_100", Name: "打卡100次", Description: "在聊天室累计打卡100次", check: func(ctx *achievementContext) (bool, error) {
+		count, err := ctx.totalCheckIns()
+		return count >= 100, err
+	}},
+	{Code: "STREAK_7", Name: "连续打卡7天", Description: "在每日打卡任务上连续打卡7天", check: func(ctx *achievementContext) (bool, error) {
+		streak, err := ctx.dailyStreak()
+		return streak >= 7, err
+	}},
+	{Code: "STREAK_30", Name: "连续打卡30天", Description: "在每日打卡任务上连续打卡30天", check: func(ctx *achievementContext) (bool, error) {
+		streak, err := ctx.dailyStreak()
+		return streak >= 30, err
+	}},
+	{Code: "STUDY_100_HOURS", Name: "学习100小时", Description: "在聊天室累计记录学习时长100小时", check: func(ctx *achievementContext) (bool, error) {
+		minutes, err := ctx.totalDuration()
+		return minutes >= 100*60, err
+	}},
+	// 与其他徽章一样每人只发放一次；SubmitCheckIn锁定聊天室后写入打卡，ID更小的当天打卡一定已提交
+	{Code: "FIRST_OF_DAY", Name: "抢到首卡", Description: "第一次成为聊天室当天第一个打卡的成员（只发放一次）", check: func(ctx *achievementContext) (bool, error) {
+		if ctx.checkIn.IsMakeup {
+			return false, nil
+		}
+		var earlier int64
+		err := ctx.db.Model(&models.CheckIn{}).
+			Where("chat_room_id = ? AND check_date = ? AND is_makeup = ? AND id < ?",
+				ctx.checkIn.ChatRoomID, ctx.checkIn.CheckDate, false, ctx.checkIn.ID).
+			Count(&earlier).Error
+		return earlier == 0, err
+	}},
+}
+
+// achievementContext 检查规则时使用的打卡上下文，统计数据按需查询并缓存
+type achievementContext struct {
+	db      *gorm.DB
+	service *CheckInService
+	checkIn *models.CheckIn
+	task    *models.CheckInTask
+
+	count    *int64
+	duration *int64
+	streak   *int
+}
+
+// totalCheckIns 用户在聊天室的累计打卡次数
+func (ctx *achievementContext) totalCheckIns() (int64, error) {
+	if ctx.count == nil {
+		var count int64
+		if err := ctx.db.Model(&models.CheckIn{}).
+			Where("chat_room_id = ? AND user_id = ?", ctx.checkIn.ChatRoomID, ctx.checkIn.UserID).
+			Count(&count).Error; err != nil {
+			return 0, err
+		}
+		ctx.count = &count
+	}
+	return *ctx.count, nil
+}
+
+// totalDuration 用户在聊天室的累计学习时长（分钟）
+func (ctx *achievementContext) totalDuration() (int64, error) {
+	if ctx.duration == nil {
+		var duration int64
+		if err := ctx.db.Model(&models.CheckIn{}).
+			Select("COALESCE(SUM(duration_minutes), 0)").
+			Where("chat_room_id = ? AND user_id = ?", ctx.checkIn.ChatRoomID, ctx.checkIn.UserID).
+			Scan(&duration).Error; err != nil {
+			return 0, err
+		}
+		ctx.duration = &duration
+	}
+	return *ctx.duration, nil
+}
+
+// dailyStreak 用户在本次打卡的每日任务上的当前连续打卡天数，非每日任务返回0
+func (ctx *achievementContext) dailyStreak() (int, error) {
+	if ctx.streak == nil {
+		streak := 0
+		if ctx.task != nil && ctx.task.Cycle == "DAILY" {
+			streaks, err := ctx.service.GetCheckInStreaks(ctx.checkIn.ChatRoomID, &ctx.checkIn.UserID, &ctx.task.ID)
+			if err != nil {
+				return 0, err
+			}
+			if len(streaks) > 0 {
+				streak = streaks[0].CurrentStreak
+			}
+		}
+		ctx.streak = &streak
+	}
+	return *ctx.streak, nil
+}
+
+type AchievementService struct {
+	db             *gorm.DB
+	checkInService *CheckInService
+}
+
+func NewAchievementService() *AchievementService {
+	return &AchievementService{
+		db:             database.GetDB(),
+		checkInService: NewCheckInService(),
+	}
+}
+
+// GetBadgeRules 获取所有成就徽章的定义
+func (s *AchievementService) GetBadgeRules() []BadgeRule {
+	return badgeRules
+}
+
+// EvaluateCheckIn 打卡（含补卡通过）后检查所有规则，返回本次新获得的徽章
+func (s *AchievementService) EvaluateCheckIn(checkInID int64) ([]models.UserBadge, error) {
+	var checkIn models.CheckIn
+	if err := s.db.First(&checkIn, checkInID).Error; err != nil {
+		return nil, errors.New("打卡记录不存在")
+	}
+
+	ctx := &achievementContext{db: s.db, service: s.checkInService, checkIn: &checkIn}
+	if checkIn.TaskID != nil {
+		var task models.CheckInTask
+		if err := s.db.First(&task, *checkIn.TaskID).Error; err == nil {
+			ctx.task = &task
+		}
+	}
+
+	// 跳过已获得的徽章，避免重复计算
+	var owned []string
+	if err := s.db.Model(&models.UserBadge{}).
+		Where("chat_room_id = ? AND user_id = ?", checkIn.ChatRoomID, checkIn.UserID).
+		Pluck("badge_code", &owned).Error; err != nil {
+		return nil, err
+	}
+	ownedCodes := make(map[string]bool, len(owned))
+	for _, code := range owned {
+		ownedCodes[code] = true
+	}
+
+	awarded := make([]models.UserBadge, 0)
+	for _, rule := range badgeRules {
+		if ownedCodes[rule.Code] {
+			continue
+		}
+
+		achieved, err := rule.check(ctx)
+		if err != nil {
+			return awarded, err
+		}
+		if !achieved {
+			continue
+		}
+
+		// 并发打卡时由唯一索引保证同一徽章只发放一次
+		badge := models.UserBadge{
+			ChatRoomID: checkIn.ChatRoomID,
+			UserID:     checkIn.UserID,
+			BadgeCode:  rule.Code,
+			BadgeName:  rule.Name,
+			CheckInID:  &checkIn.ID,
+			AwardedAt:  time.Now(),
+		}
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&badge)
+		if result.Error != nil {
+			return awarded, result.Error
+		}
+		if result.RowsAffected > 0 {
+			awarded = append(awarded, badge)
+		}
+	}
+
+	return awarded, nil
+}
+
+// GetUserBadges 获取用户在聊天室获得的徽章
+func (s *AchievementService) GetUserBadges(roomID, userID int64) ([]models.UserBadge, error) {
+	var badges []models.UserBadge
+	err := s.db.Where("chat_room_id = ? AND user_id = ?", roomID, userID).
+		Order("awarded_at ASC").
+		Find(&badges).Error
+	return badges, err
+}
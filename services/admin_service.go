@@ -0,0 +1,227 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminService struct {
+	db *gorm.DB
+}
+
+func NewAdminService() *AdminService {
+	return &AdminService{
+		db: database.GetDB(),
+	}
+}
+
+// GetActiveAdmin 获取处于启用状态的管理员账户
+func (s *AdminService) GetActiveAdmin(userID int64) (*models.Admin, error) {
+	var admin models.Admin
+	if err := s.db.Where("user_id = ? AND is_active = ?", userID, true).First(&admin).Error; err != nil {
+		return nil, errors.New("无管理员权限")
+	}
+	return &admin, nil
+}
+
+// GetPendingChatRooms 获取待审核的聊天室列表
+func (s *AdminService) GetPendingChatRooms(page, pageSize int) ([]models.ChatRoom, int64, error) {
+	var rooms []models.ChatRoom
+	var total int64
+
+	query := s.db.Model(&models.ChatRoom{}).Where("is_active = ? AND review_status = ?", true, "PENDING")
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询，先提交的先审核
+	offset := (page - 1) * pageSize
+	err := query.Preload("Creator").
+		Order("created_at ASC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&rooms).Error
+
+	return rooms, total, err
+}
+
+// ReviewChatRoom 审核聊天室
+func (s *AdminService) ReviewChatRoom(adminUserID, roomID int64, approved bool, reason string) error {
+	var room models.ChatRoom
+	if err := s.db.First(&room, roomID).Error; err != nil {
+		return errors.New("聊天室不存在")
+	}
+
+	status := "REJECTED"
+	if approved {
+		status = "APPROVED"
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&room).Updates(map[string]interface{}{
+			"is_approved":   approved,
+			"review_status": status,
+			"review_reason": reason,
+			"reviewer_id":   adminUserID,
+			"reviewed_at":   time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, adminUserID, "REVIEW_CHATROOM", "CHATROOM", roomID, &roomID,
+			map[string]interface{}{"reviewStatus": room.ReviewStatus, "isApproved": room.IsApproved},
+			map[string]interface{}{"reviewStatus": status, "isApproved": approved, "reason": reason},
+		)
+	})
+}
+
+// DeactivateChatRoom 强制停用聊天室
+func (s *AdminService) DeactivateChatRoom(adminUserID, roomID int64, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.deactivateChatRoom(tx, adminUserID, roomID, reason)
+	})
+}
+
+// deactivateChatRoom 在事务中停用聊天室并记录审计日志
+func (s *AdminService) deactivateChatRoom(tx *gorm.DB, adminUserID, roomID int64, reason string) error {
+	var room models.ChatRoom
+	if err := tx.First(&room, roomID).Error; err != nil {
+		return errors.New("聊天室不存在")
+	}
+
+	if !room.IsActive {
+		return errors.New("聊天室已停用")
+	}
+
+	if err := tx.Model(&room).Updates(map[string]interface{}{
+		"is_active":         false,
+		"deactivate_reason": reason,
+	}).Error; err != nil {
+		return err
+	}
+
+	return writeAuditLog(tx, adminUserID, "DEACTIVATE_CHATROOM", "CHATROOM", roomID, &roomID,
+		map[string]interface{}{"isActive": true},
+		map[string]interface{}{"isActive": false, "reason": reason},
+	)
+}
+
+// SetUserStatus 启用或禁用平台用户
+func (s *AdminService) SetUserStatus(admin *models.Admin, userID int64, status string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.setUserStatus(tx, admin, userID, status)
+	})
+}
+
+// setUserStatus 在事务中修改用户状态并记录审计日志
+func (s *AdminService) setUserStatus(tx *gorm.DB, admin *models.Admin, userID int64, status string) error {
+	if status != "ACTIVE" && status != "DISABLED" {
+		return errors.New("无效的用户状态")
+	}
+
+	if admin.UserID == userID {
+		return errors.New("不能修改自己的账户状态")
+	}
+
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+
+	if user.Status == "DELETED" {
+		return errors.New("用户已注销")
+	}
+
+	// 版主不能禁用其他管理员
+	if admin.Role != "SUPER_ADMIN" {
+		var targetAdmin models.Admin
+		if err := tx.Where("user_id = ? AND is_active = ?", userID, true).First(&targetAdmin).Error; err == nil {
+			return errors.New("版主不能修改管理员的账户状态")
+		}
+	}
+
+	if err := tx.Model(&user).Update("status", status).Error; err != nil {
+		return err
+	}
+
+	return writeAuditLog(tx, admin.UserID, "SET_USER_STATUS", "USER", userID, nil,
+		map[string]interface{}{"status": user.Status},
+		map[string]interface{}{"status": status},
+	)
+}
+
+// GetAdmins 获取管理员列表
+func (s *AdminService) GetAdmins() ([]models.Admin, error) {
+	var admins []models.Admin
+	err := s.db.Preload("User").Order("created_at ASC").Find(&admins).Error
+	return admins, err
+}
+
+// CreateAdmin 添加管理员账户（超级管理员操作）
+func (s *AdminService) CreateAdmin(operator *models.Admin, userID int64, role string) (*models.Admin, error) {
+	if role != "SUPER_ADMIN" && role != "MODERATOR" {
+		return nil, errors.New("无效的管理员角色")
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	var existing models.Admin
+	if err := s.db.Where("user_id = ?", userID).First(&existing).Error; err == nil {
+		return nil, errors.New("该用户已是管理员")
+	}
+
+	admin := &models.Admin{
+		UserID:    userID,
+		Role:      role,
+		IsActive:  true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(admin).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operator.UserID, "CREATE_ADMIN", "ADMIN", admin.ID, nil, nil,
+			map[string]interface{}{"userId": userID, "role": role},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return admin, nil
+}
+
+// UpdateAdmin 修改管理员角色或启用状态（超级管理员操作）
+func (s *AdminService) UpdateAdmin(operator *models.Admin, adminID int64, updates map[string]interface{}) error {
+	var admin models.Admin
+	if err := s.db.First(&admin, adminID).Error; err != nil {
+		return errors.New("管理员不存在")
+	}
+
+	if admin.ID == operator.ID {
+		return errors.New("不能修改自己的管理员账户")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		before := map[string]interface{}{"role": admin.Role, "isActive": admin.IsActive}
+		if err := tx.Model(&admin).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operator.UserID, "UPDATE_ADMIN", "ADMIN", adminID, nil, before,
+			map[string]interface{}{"role": admin.Role, "isActive": admin.IsActive},
+		)
+	})
+}
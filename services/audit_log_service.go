@@ -0,0 +1,110 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AuditLogService struct {
+	db *gorm.DB
+}
+
+func NewAuditLogService() *AuditLogService {
+	return &AuditLogService{
+		db: database.GetDB(),
+	}
+}
+
+// AuditLogFilter 审计日志查询条件
+type AuditLogFilter struct {
+	ChatRoomID *int64
+	ActorID    *int64
+	Action     string
+	StartTime  *time.Time
+	EndTime    *time.Time
+}
+
+// GetAuditLogs 按条件分页查询审计日志（平台管理员可跨聊天室查询）
+func (s *AuditLogService) GetAuditLogs(filter AuditLogFilter, page, pageSize int) ([]models.AuditLog, int64, error) {
+	var logs []models.AuditLog
+	var total int64
+
+	query := s.db.Model(&models.AuditLog{})
+
+	if filter.ChatRoomID != nil {
+		query = query.Where("chat_room_id = ?", *filter.ChatRoomID)
+	}
+
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	if filter.StartTime != nil {
+		query = query.Where("created_at >= ?", *filter.StartTime)
+	}
+
+	if filter.EndTime != nil {
+		query = query.Where("created_at < ?", *filter.EndTime)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询，按时间倒序
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&logs).Error
+
+	return logs, total, err
+}
+
+// GetRoomAuditLogs 查询聊天室的审计日志（仅房主可查看）
+func (s *AuditLogService) GetRoomAuditLogs(roomID, operatorID int64, filter AuditLogFilter, page, pageSize int) ([]models.AuditLog, int64, error) {
+	var member models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ? AND user_id = ? AND role = ?", roomID, operatorID, "OWNER").First(&member).Error; err != nil {
+		return nil, 0, errors.New("只有房主可以查看审计日志")
+	}
+
+	filter.ChatRoomID = &roomID
+	return s.GetAuditLogs(filter, page, pageSize)
+}
+
+// writeAuditLog 追加一条审计日志（应在业务操作所在的事务中调用）
+func writeAuditLog(tx *gorm.DB, actorID int64, action, targetType string, targetID int64, chatRoomID *int64, before, after interface{}) error {
+	auditLog := &models.AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		ChatRoomID: chatRoomID,
+		Before:     marshalAuditValue(before),
+		After:      marshalAuditValue(after),
+		CreatedAt:  time.Now(),
+	}
+	return tx.Create(auditLog).Error
+}
+
+// marshalAuditValue 将变更前后的值序列化为JSON字符串
+func marshalAuditValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -3,19 +3,24 @@ package services
 import (
 	"campus-canvas-chat/database"
 	"campus-canvas-chat/models"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ChatRoomService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	contentFilter *ContentFilterService
 }
 
 func NewChatRoomService() *ChatRoomService {
 	return &ChatRoomService{
-		db: database.GetDB(),
+		db:            database.GetDB(),
+		contentFilter: NewContentFilterService(),
 	}
 }
 
@@ -27,6 +32,22 @@ func (s *ChatRoomService) CreateChatRoom(room *models.ChatRoom) error {
 		return errors.New("创建者不存在")
 	}
 
+	if user.Status != "ACTIVE" {
+		return errors.New("账号已被禁用")
+	}
+
+	// 敏感词过滤聊天室名称和描述
+	name, err := s.contentFilter.Apply("CHATROOM", room.CreatorID, nil, room.Name)
+	if err != nil {
+		return err
+	}
+	description, err := s.contentFilter.Apply("CHATROOM", room.CreatorID, nil, room.Description)
+	if err != nil {
+		return err
+	}
+	room.Name = name
+	room.Description = description
+
 	// 创建聊天室
 	if err := s.db.Create(room).Error; err != nil {
 		return err
@@ -72,46 +93,168 @@ func (s *ChatRoomService) GetChatRoomList(page, pageSize int, category string, i
 
 // GetChatRoomByID 根据ID获取聊天室详情
 func (s *ChatRoomService) GetChatRoomByID(roomID int64) (*models.ChatRoom, error) {
+	// 清除已到期的禁言
+	s.clearExpiredMutes(roomID)
+
 	var room models.ChatRoom
 	err := s.db.Preload("Creator").Preload("Members", func(db *gorm.DB) *gorm.DB {
-		return db.Select("id, chat_room_id, user_id, role, is_muted, joined_at, updated_at")
+		return db.Select("id, chat_room_id, user_id, role, is_muted, muted_until, mute_reason, joined_at, updated_at")
 	}).Preload("Members.User").First(&room, roomID).Error
 	if err != nil {
 		return nil, err
 	}
+
+	// 一次查询取出成员的打卡成就徽章
+	var badges []models.UserBadge
+	if err := s.db.Where("chat_room_id = ?", roomID).Order("awarded_at ASC").Find(&badges).Error; err != nil {
+		return nil, err
+	}
+	memberBadges := make(map[int64][]models.UserBadge)
+	for _, badge := range badges {
+		memberBadges[badge.UserID] = append(memberBadges[badge.UserID], badge)
+	}
+	for i := range room.Members {
+		room.Members[i].Badges = memberBadges[room.Members[i].UserID]
+	}
+
 	return &room, nil
 }
 
 // JoinChatRoom 加入聊天室
-func (s *ChatRoomService) JoinChatRoom(roomID, userID int64) error {
+// 需审批的聊天室会创建入群申请并返回该申请；直接加入成功时返回nil
+func (s *ChatRoomService) JoinChatRoom(roomID, userID int64, inviteCode, message string) (*models.ChatRoomJoinRequest, error) {
 	// 检查聊天室是否存在且已审核
 	var room models.ChatRoom
 	if err := s.db.Where("id = ? AND is_active = ? AND is_approved = ?", roomID, true, true).First(&room).Error; err != nil {
-		return errors.New("聊天室不存在或未审核")
+		return nil, errors.New("聊天室不存在或未审核")
+	}
+
+	// 检查用户账号状态
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	if user.Status != "ACTIVE" {
+		return nil, errors.New("账号已被禁用")
+	}
+
+	// 检查用户是否被封禁
+	if s.IsUserBanned(roomID, userID) {
+		return nil, errors.New("您已被该聊天室封禁")
 	}
 
 	// 检查用户是否已经是成员
 	var existingMember models.ChatRoomMember
 	if err := s.db.Where("chat_room_id = ? AND user_id = ?", roomID, userID).First(&existingMember).Error; err == nil {
-		return errors.New("用户已经是该聊天室成员")
+		return nil, errors.New("用户已经是该聊天室成员")
+	}
+
+	// 持有邀请码时直接通过邀请码加入（不受加入方式限制）
+	if inviteCode != "" {
+		return nil, s.db.Transaction(func(tx *gorm.DB) error {
+			if err := s.useInviteCode(tx, roomID, inviteCode); err != nil {
+				return err
+			}
+			return s.addMember(tx, &room, userID)
+		})
+	}
+
+	switch room.JoinPolicy {
+	case "INVITE":
+		return nil, errors.New("该聊天室仅限邀请加入")
+	case "APPROVAL":
+		request := &models.ChatRoomJoinRequest{
+			ChatRoomID: roomID,
+			UserID:     userID,
+			Message:    message,
+			Status:     "PENDING",
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		}
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// 锁定聊天室记录，重复提交的申请依次检查，避免产生多条待审核申请
+			if err := lockChatRoom(tx, roomID); err != nil {
+				return err
+			}
+
+			// 检查是否已有待审核的申请
+			var pending int64
+			if err := tx.Model(&models.ChatRoomJoinRequest{}).
+				Where("chat_room_id = ? AND user_id = ? AND status = ?", roomID, userID, "PENDING").
+				Count(&pending).Error; err != nil {
+				return err
+			}
+			if pending > 0 {
+				return errors.New("已提交入群申请，请等待审核")
+			}
+
+			return tx.Create(request).Error
+		})
+		if err != nil {
+			return nil, err
+		}
+		return request, nil
+	}
+
+	return nil, s.db.Transaction(func(tx *gorm.DB) error {
+		return s.addMember(tx, &room, userID)
+	})
+}
+
+// lockChatRoom 在事务中锁定聊天室记录，串行化同一聊天室的成员变更
+func lockChatRoom(tx *gorm.DB, roomID int64) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.ChatRoom{}, roomID).Error; err != nil {
+		return errors.New("聊天室不存在")
+	}
+	return nil
+}
+
+// addMember 检查人数限制并添加普通成员，需在事务中调用
+func (s *ChatRoomService) addMember(tx *gorm.DB, room *models.ChatRoom, userID int64) error {
+	// 锁定聊天室记录，并发加入时依次检查人数限制
+	if err := lockChatRoom(tx, room.ID); err != nil {
+		return err
 	}
 
 	// 检查房间人数限制
 	var memberCount int64
-	s.db.Model(&models.ChatRoomMember{}).Where("chat_room_id = ?", roomID).Count(&memberCount)
+	if err := tx.Model(&models.ChatRoomMember{}).Where("chat_room_id = ?", room.ID).Count(&memberCount).Error; err != nil {
+		return err
+	}
 	if int(memberCount) >= room.MaxMembers {
 		return errors.New("聊天室人数已满")
 	}
 
 	// 添加成员
 	member := &models.ChatRoomMember{
-		ChatRoomID: roomID,
+		ChatRoomID: room.ID,
 		UserID:     userID,
 		Role:       "MEMBER",
 		JoinedAt:   time.Now(),
 	}
 
-	return s.db.Create(member).Error
+	return tx.Create(member).Error
+}
+
+// useInviteCode 校验并消耗一次邀请码
+func (s *ChatRoomService) useInviteCode(tx *gorm.DB, roomID int64, code string) error {
+	var invite models.ChatRoomInvite
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ? AND chat_room_id = ? AND is_active = ?", code, roomID, true).
+		First(&invite).Error; err != nil {
+		return errors.New("邀请码无效")
+	}
+
+	if invite.ExpiresAt != nil && invite.ExpiresAt.Before(time.Now()) {
+		return errors.New("邀请码已过期")
+	}
+
+	if invite.MaxUses > 0 && invite.UsedCount >= invite.MaxUses {
+		return errors.New("邀请码使用次数已达上限")
+	}
+
+	return tx.Model(&invite).Update("used_count", gorm.Expr("used_count + ?", 1)).Error
 }
 
 // LeaveChatRoom 离开聊天室
@@ -138,15 +281,19 @@ func (s *ChatRoomService) DeleteChatRoom(roomID, userID int64) error {
 	}
 
 	// 软删除聊天室
-	return s.db.Model(&models.ChatRoom{}).Where("id = ?", roomID).Updates(map[string]interface{}{
-		"is_active":  false,
-		"deleted_at": time.Now(),
-	}).Error
-}
-
-// ApproveChatRoom 审核聊天室（管理员操作）
-func (s *ChatRoomService) ApproveChatRoom(roomID int64, approved bool) error {
-	return s.db.Model(&models.ChatRoom{}).Where("id = ?", roomID).Update("is_approved", approved).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ChatRoom{}).Where("id = ?", roomID).Updates(map[string]interface{}{
+			"is_active":  false,
+			"deleted_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, userID, "DELETE_CHATROOM", "CHATROOM", roomID, &roomID,
+			map[string]interface{}{"isActive": true},
+			map[string]interface{}{"isActive": false},
+		)
+	})
 }
 
 // GetUserChatRooms 获取用户加入的聊天室列表
@@ -169,9 +316,8 @@ func (s *ChatRoomService) UpdateMemberRole(roomID, operatorID, targetUserID int6
 		return errors.New("不能修改自己的角色")
 	}
 
-	// 检查角色是否有效
+	// 检查角色是否有效（房主身份只能通过转让获得）
 	validRoles := map[string]bool{
-		"OWNER":  true,
 		"ADMIN":  true,
 		"MEMBER": true,
 	}
@@ -211,43 +357,471 @@ func (s *ChatRoomService) UpdateMemberRole(roomID, operatorID, targetUserID int6
 	}
 
 	// 更新目标用户角色
-	return s.db.Model(&models.ChatRoomMember{}).
-		Where("chat_room_id = ? AND user_id = ?", roomID, targetUserID).
-		Update("role", newRole).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ChatRoomMember{}).
+			Where("chat_room_id = ? AND user_id = ?", roomID, targetUserID).
+			Update("role", newRole).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "UPDATE_MEMBER_ROLE", "USER", targetUserID, &roomID,
+			map[string]interface{}{"role": targetMember.Role},
+			map[string]interface{}{"role": newRole},
+		)
+	})
 }
 
-// MuteMember 禁言成员
-func (s *ChatRoomService) MuteMember(roomID, operatorID, targetUserID int64, muted bool) error {
-	// 检查操作者权限
+// TransferOwnership 转让房主（仅房主可操作，原房主降为管理员）
+func (s *ChatRoomService) TransferOwnership(roomID, ownerID, newOwnerID int64) error {
+	if ownerID == newOwnerID {
+		return errors.New("不能将房主转让给自己")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 检查操作者是否是房主，先锁定房主记录，同一房主的并发转让依次执行
+		var ownerMember models.ChatRoomMember
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("chat_room_id = ? AND user_id = ? AND role = ?", roomID, ownerID, "OWNER").
+			First(&ownerMember).Error; err != nil {
+			return errors.New("只有房主可以转让房主权限")
+		}
+
+		// 检查目标用户是否是成员
+		var targetMember models.ChatRoomMember
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("chat_room_id = ? AND user_id = ?", roomID, newOwnerID).
+			First(&targetMember).Error; err != nil {
+			return errors.New("目标用户不是该聊天室成员")
+		}
+
+		// 原房主降为管理员，仅在其仍是房主时更新
+		result := tx.Model(&models.ChatRoomMember{}).
+			Where("id = ? AND role = ?", ownerMember.ID, "OWNER").
+			Update("role", "ADMIN")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("只有房主可以转让房主权限")
+		}
+
+		// 目标成员升为房主
+		if err := tx.Model(&targetMember).Update("role", "OWNER").Error; err != nil {
+			return err
+		}
+
+		// 更新聊天室的房主
+		if err := tx.Model(&models.ChatRoom{}).Where("id = ?", roomID).Update("creator_id", newOwnerID).Error; err != nil {
+			return err
+		}
+
+		// 记录审计日志
+		return writeAuditLog(tx, ownerID, "TRANSFER_OWNER", "CHATROOM", roomID, &roomID,
+			map[string]interface{}{"ownerId": ownerID, "newOwnerPreviousRole": targetMember.Role},
+			map[string]interface{}{"ownerId": newOwnerID, "previousOwnerRole": "ADMIN"},
+		)
+	})
+}
+
+// checkManager 检查操作者是否是房主或管理员
+func (s *ChatRoomService) checkManager(roomID, operatorID int64) (*models.ChatRoomMember, error) {
 	var operatorMember models.ChatRoomMember
 	if err := s.db.Where("chat_room_id = ? AND user_id = ?", roomID, operatorID).First(&operatorMember).Error; err != nil {
-		return errors.New("操作者不是该聊天室成员")
+		return nil, errors.New("操作者不是该聊天室成员")
 	}
 
 	if operatorMember.Role != "OWNER" && operatorMember.Role != "ADMIN" {
-		return errors.New("权限不足")
+		return nil, errors.New("权限不足")
+	}
+
+	return &operatorMember, nil
+}
+
+// GetRoomManagerIDs 获取聊天室房主和管理员的用户ID
+func (s *ChatRoomService) GetRoomManagerIDs(roomID int64) ([]int64, error) {
+	var userIDs []int64
+	err := s.db.Model(&models.ChatRoomMember{}).
+		Where("chat_room_id = ? AND role IN ?", roomID, []string{"OWNER", "ADMIN"}).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// SetJoinPolicy 设置聊天室加入方式（仅房主可操作）
+func (s *ChatRoomService) SetJoinPolicy(roomID, operatorID int64, policy string) error {
+	validPolicies := map[string]bool{
+		"OPEN":     true,
+		"APPROVAL": true,
+		"INVITE":   true,
+	}
+	if !validPolicies[policy] {
+		return errors.New("无效的加入方式")
+	}
+
+	var member models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ? AND user_id = ? AND role = ?", roomID, operatorID, "OWNER").First(&member).Error; err != nil {
+		return errors.New("只有房主可以设置加入方式")
+	}
+
+	var room models.ChatRoom
+	if err := s.db.First(&room, roomID).Error; err != nil {
+		return errors.New("聊天室不存在")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&room).Update("join_policy", policy).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "SET_JOIN_POLICY", "CHATROOM", roomID, &roomID,
+			map[string]interface{}{"joinPolicy": room.JoinPolicy},
+			map[string]interface{}{"joinPolicy": policy},
+		)
+	})
+}
+
+// GetJoinRequests 获取入群申请列表（房主和管理员可查看）
+func (s *ChatRoomService) GetJoinRequests(roomID, operatorID int64, status string) ([]models.ChatRoomJoinRequest, error) {
+	if _, err := s.checkManager(roomID, operatorID); err != nil {
+		return nil, err
+	}
+
+	var requests []models.ChatRoomJoinRequest
+	query := s.db.Where("chat_room_id = ?", roomID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	err := query.Preload("User").Order("created_at DESC").Find(&requests).Error
+	return requests, err
+}
+
+// ReviewJoinRequest 审批入群申请（房主和管理员可操作）
+func (s *ChatRoomService) ReviewJoinRequest(roomID, requestID, operatorID int64, approved bool) (*models.ChatRoomJoinRequest, error) {
+	if _, err := s.checkManager(roomID, operatorID); err != nil {
+		return nil, err
+	}
+
+	var request models.ChatRoomJoinRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND chat_room_id = ?", requestID, roomID).
+			First(&request).Error; err != nil {
+			return errors.New("入群申请不存在")
+		}
+
+		if request.Status != "PENDING" {
+			return errors.New("该申请已处理")
+		}
+
+		if approved {
+			if s.IsUserBanned(roomID, request.UserID) {
+				return errors.New("该用户已被封禁，无法通过申请")
+			}
+
+			var room models.ChatRoom
+			if err := tx.Where("id = ? AND is_active = ?", roomID, true).First(&room).Error; err != nil {
+				return errors.New("聊天室不存在")
+			}
+
+			var existingMember models.ChatRoomMember
+			if err := tx.Where("chat_room_id = ? AND user_id = ?", roomID, request.UserID).First(&existingMember).Error; err != nil {
+				if err := s.addMember(tx, &room, request.UserID); err != nil {
+					return err
+				}
+			}
+		}
+
+		status := "REJECTED"
+		if approved {
+			status = "APPROVED"
+		}
+		now := time.Now()
+		request.Status = status
+		request.ReviewerID = &operatorID
+		request.ReviewedAt = &now
+
+		if err := tx.Model(&request).Updates(map[string]interface{}{
+			"status":      status,
+			"reviewer_id": operatorID,
+			"reviewed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "REVIEW_JOIN_REQUEST", "USER", request.UserID, &roomID,
+			map[string]interface{}{"requestId": request.ID, "status": "PENDING"},
+			map[string]interface{}{"requestId": request.ID, "status": status},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// CreateInvite 创建邀请码（房主和管理员可操作）
+func (s *ChatRoomService) CreateInvite(roomID, operatorID int64, maxUses int, expiresIn time.Duration) (*models.ChatRoomInvite, error) {
+	if _, err := s.checkManager(roomID, operatorID); err != nil {
+		return nil, err
+	}
+
+	// 生成随机邀请码
+	randomBytes := make([]byte, 12)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, errors.New("生成邀请码失败: " + err.Error())
+	}
+
+	invite := &models.ChatRoomInvite{
+		ChatRoomID: roomID,
+		Code:       base64.RawURLEncoding.EncodeToString(randomBytes),
+		CreatorID:  operatorID,
+		MaxUses:    maxUses,
+		IsActive:   true,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invite).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "CREATE_INVITE", "INVITE", invite.ID, &roomID, nil,
+			map[string]interface{}{"maxUses": invite.MaxUses, "expiresAt": invite.ExpiresAt},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// GetInvites 获取聊天室的有效邀请码列表（房主和管理员可查看）
+func (s *ChatRoomService) GetInvites(roomID, operatorID int64) ([]models.ChatRoomInvite, error) {
+	if _, err := s.checkManager(roomID, operatorID); err != nil {
+		return nil, err
+	}
+
+	var invites []models.ChatRoomInvite
+	err := s.db.Where("chat_room_id = ? AND is_active = ?", roomID, true).
+		Order("created_at DESC").
+		Find(&invites).Error
+	return invites, err
+}
+
+// RevokeInvite 作废邀请码（房主和管理员可操作）
+func (s *ChatRoomService) RevokeInvite(roomID, inviteID, operatorID int64) error {
+	if _, err := s.checkManager(roomID, operatorID); err != nil {
+		return err
+	}
+
+	var invite models.ChatRoomInvite
+	if err := s.db.Where("id = ? AND chat_room_id = ? AND is_active = ?", inviteID, roomID, true).First(&invite).Error; err != nil {
+		return errors.New("邀请码不存在")
 	}
 
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&invite).Update("is_active", false).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "REVOKE_INVITE", "INVITE", inviteID, &roomID,
+			map[string]interface{}{"isActive": true},
+			map[string]interface{}{"isActive": false},
+		)
+	})
+}
+
+// MuteMember 禁言或解除禁言成员，duration为0表示永久禁言
+func (s *ChatRoomService) MuteMember(roomID, operatorID, targetUserID int64, muted bool, duration time.Duration, reason string) (*models.ChatRoomMember, error) {
+	// 检查操作者权限
+	operatorMember, err := s.checkManager(roomID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var targetMember *models.ChatRoomMember
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		targetMember, err = s.muteMember(tx, roomID, operatorID, operatorMember.Role, targetUserID, muted, duration, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return targetMember, nil
+}
+
+// platformMuteMember 平台管理员处理举报时禁言成员，不要求操作者是聊天室房主或管理员
+func (s *ChatRoomService) platformMuteMember(tx *gorm.DB, roomID, adminUserID, targetUserID int64, duration time.Duration, reason string) (*models.ChatRoomMember, error) {
+	return s.muteMember(tx, roomID, adminUserID, "", targetUserID, true, duration, reason)
+}
+
+// muteMember 在事务中更新成员禁言状态并记录审计日志，operatorRole为空表示平台管理员
+func (s *ChatRoomService) muteMember(tx *gorm.DB, roomID, operatorID int64, operatorRole string, targetUserID int64, muted bool, duration time.Duration, reason string) (*models.ChatRoomMember, error) {
 	// 获取目标用户信息
 	var targetMember models.ChatRoomMember
-	if err := s.db.Where("chat_room_id = ? AND user_id = ?", roomID, targetUserID).First(&targetMember).Error; err != nil {
-		return errors.New("目标用户不是该聊天室成员")
+	if err := tx.Where("chat_room_id = ? AND user_id = ?", roomID, targetUserID).First(&targetMember).Error; err != nil {
+		return nil, errors.New("目标用户不是该聊天室成员")
 	}
 
 	// 不能禁言房主
 	if targetMember.Role == "OWNER" {
-		return errors.New("不能禁言房主")
+		return nil, errors.New("不能禁言房主")
 	}
 
 	// 管理员不能禁言其他管理员
-	if operatorMember.Role == "ADMIN" && targetMember.Role == "ADMIN" {
-		return errors.New("管理员不能禁言其他管理员")
+	if operatorRole == "ADMIN" && targetMember.Role == "ADMIN" {
+		return nil, errors.New("管理员不能禁言其他管理员")
+	}
+
+	before := map[string]interface{}{
+		"isMuted":    targetMember.IsMuted,
+		"mutedUntil": targetMember.MutedUntil,
+		"muteReason": targetMember.MuteReason,
 	}
 
 	// 更新禁言状态
-	return s.db.Model(&models.ChatRoomMember{}).
+	updates := map[string]interface{}{
+		"is_muted":    muted,
+		"muted_until": nil,
+		"mute_reason": "",
+	}
+	targetMember.IsMuted = muted
+	targetMember.MutedUntil = nil
+	targetMember.MuteReason = ""
+
+	if muted {
+		updates["mute_reason"] = reason
+		targetMember.MuteReason = reason
+		if duration > 0 {
+			mutedUntil := time.Now().Add(duration)
+			updates["muted_until"] = mutedUntil
+			targetMember.MutedUntil = &mutedUntil
+		}
+	}
+
+	if err := tx.Model(&models.ChatRoomMember{}).
 		Where("chat_room_id = ? AND user_id = ?", roomID, targetUserID).
-		Update("is_muted", muted).Error
+		Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	if err := writeAuditLog(tx, operatorID, "MUTE_MEMBER", "USER", targetUserID, &roomID, before,
+		map[string]interface{}{
+			"isMuted":    targetMember.IsMuted,
+			"mutedUntil": targetMember.MutedUntil,
+			"muteReason": targetMember.MuteReason,
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	return &targetMember, nil
+}
+
+// SetAllMuted 开启或关闭全员禁言（仅房主可操作）
+func (s *ChatRoomService) SetAllMuted(roomID, operatorID int64, enabled bool) error {
+	var member models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ? AND user_id = ? AND role = ?", roomID, operatorID, "OWNER").First(&member).Error; err != nil {
+		return errors.New("只有房主可以设置全员禁言")
+	}
+
+	var room models.ChatRoom
+	if err := s.db.First(&room, roomID).Error; err != nil {
+		return errors.New("聊天室不存在")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&room).Update("all_muted", enabled).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "SET_ALL_MUTED", "CHATROOM", roomID, &roomID,
+			map[string]interface{}{"allMuted": room.AllMuted},
+			map[string]interface{}{"allMuted": enabled},
+		)
+	})
+}
+
+// SetSlowMode 设置慢速模式的发言间隔（仅房主可操作），seconds为0表示关闭
+func (s *ChatRoomService) SetSlowMode(roomID, operatorID int64, seconds int) error {
+	var member models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ? AND user_id = ? AND role = ?", roomID, operatorID, "OWNER").First(&member).Error; err != nil {
+		return errors.New("只有房主可以设置慢速模式")
+	}
+
+	var room models.ChatRoom
+	if err := s.db.First(&room, roomID).Error; err != nil {
+		return errors.New("聊天室不存在")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&room).Update("slow_mode_seconds", seconds).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "SET_SLOW_MODE", "CHATROOM", roomID, &roomID,
+			map[string]interface{}{"slowModeSeconds": room.SlowModeSeconds},
+			map[string]interface{}{"slowModeSeconds": seconds},
+		)
+	})
+}
+
+// SetTimezone 设置聊天室打卡使用的时区（仅房主可操作），timezone为空表示使用校园默认时区
+func (s *ChatRoomService) SetTimezone(roomID, operatorID int64, timezone string) error {
+	var member models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ? AND user_id = ? AND role = ?", roomID, operatorID, "OWNER").First(&member).Error; err != nil {
+		return errors.New("只有房主可以设置时区")
+	}
+
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return errors.New("无效的时区")
+		}
+	}
+
+	var room models.ChatRoom
+	if err := s.db.First(&room, roomID).Error; err != nil {
+		return errors.New("聊天室不存在")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&room).Update("timezone", timezone).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "SET_TIMEZONE", "CHATROOM", roomID, &roomID,
+			map[string]interface{}{"timezone": room.Timezone},
+			map[string]interface{}{"timezone": timezone},
+		)
+	})
+}
+
+// clearExpiredMutes 清除聊天室内已到期的禁言
+func (s *ChatRoomService) clearExpiredMutes(roomID int64) error {
+	return s.db.Model(&models.ChatRoomMember{}).
+		Where("chat_room_id = ? AND is_muted = ? AND muted_until IS NOT NULL AND muted_until <= ?", roomID, true, time.Now()).
+		Updates(map[string]interface{}{
+			"is_muted":    false,
+			"muted_until": nil,
+			"mute_reason": "",
+		}).Error
+}
+
+// isMuteActive 判断成员的禁言是否仍然有效（定时禁言到期后视为未禁言）
+func isMuteActive(member *models.ChatRoomMember) bool {
+	if !member.IsMuted {
+		return false
+	}
+	return member.MutedUntil == nil || member.MutedUntil.After(time.Now())
 }
 
 // KickMember 踢出成员
@@ -273,5 +847,132 @@ func (s *ChatRoomService) KickMember(roomID, operatorID, targetUserID int64) err
 	}
 
 	// 删除成员
-	return s.db.Delete(&targetMember).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&targetMember).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "KICK_MEMBER", "USER", targetUserID, &roomID,
+			map[string]interface{}{"role": targetMember.Role},
+			nil,
+		)
+	})
+}
+
+// BanMember 封禁用户（房主和管理员可操作），若用户是成员则同时移出聊天室，duration为0表示永久封禁
+func (s *ChatRoomService) BanMember(roomID, operatorID, targetUserID int64, duration time.Duration, reason string) error {
+	// 检查操作者权限
+	operatorMember, err := s.checkManager(roomID, operatorID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.banMember(tx, roomID, operatorID, operatorMember.Role, targetUserID, duration, reason)
+	})
+}
+
+// platformBanMember 平台管理员处理举报时封禁成员，不要求操作者是聊天室房主或管理员
+func (s *ChatRoomService) platformBanMember(tx *gorm.DB, roomID, adminUserID, targetUserID int64, duration time.Duration, reason string) error {
+	return s.banMember(tx, roomID, adminUserID, "", targetUserID, duration, reason)
+}
+
+// banMember 在事务中封禁用户并移出聊天室，operatorRole为空表示平台管理员
+func (s *ChatRoomService) banMember(tx *gorm.DB, roomID, operatorID int64, operatorRole string, targetUserID int64, duration time.Duration, reason string) error {
+	if operatorID == targetUserID {
+		return errors.New("不能封禁自己")
+	}
+
+	ban := &models.ChatRoomBan{
+		ChatRoomID: roomID,
+		UserID:     targetUserID,
+		OperatorID: operatorID,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+	if duration > 0 {
+		expiresAt := time.Now().Add(duration)
+		ban.ExpiresAt = &expiresAt
+	}
+
+	// 如果目标用户是成员，检查角色并移出聊天室
+	var targetMember models.ChatRoomMember
+	if err := tx.Where("chat_room_id = ? AND user_id = ?", roomID, targetUserID).First(&targetMember).Error; err == nil {
+		if targetMember.Role == "OWNER" {
+			return errors.New("不能封禁房主")
+		}
+
+		if operatorRole == "ADMIN" && targetMember.Role == "ADMIN" {
+			return errors.New("管理员不能封禁其他管理员")
+		}
+
+		if err := tx.Delete(&targetMember).Error; err != nil {
+			return err
+		}
+	}
+
+	// 已有封禁记录时覆盖为新的封禁
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_room_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"operator_id", "reason", "expires_at", "created_at"}),
+	}).Create(ban).Error; err != nil {
+		return err
+	}
+
+	return writeAuditLog(tx, operatorID, "BAN_MEMBER", "USER", targetUserID, &roomID,
+		map[string]interface{}{"role": targetMember.Role},
+		map[string]interface{}{"expiresAt": ban.ExpiresAt, "reason": reason},
+	)
+}
+
+// GetBans 获取聊天室的封禁列表（房主和管理员可查看）
+func (s *ChatRoomService) GetBans(roomID, operatorID int64) ([]models.ChatRoomBan, error) {
+	if _, err := s.checkManager(roomID, operatorID); err != nil {
+		return nil, err
+	}
+
+	var bans []models.ChatRoomBan
+	err := s.db.Where("chat_room_id = ? AND (expires_at IS NULL OR expires_at > ?)", roomID, time.Now()).
+		Preload("User").
+		Order("created_at DESC").
+		Find(&bans).Error
+	return bans, err
+}
+
+// UnbanUser 解除封禁（房主和管理员可操作）
+func (s *ChatRoomService) UnbanUser(roomID, operatorID, targetUserID int64) error {
+	if _, err := s.checkManager(roomID, operatorID); err != nil {
+		return err
+	}
+
+	var ban models.ChatRoomBan
+	if err := s.db.Where("chat_room_id = ? AND user_id = ?", roomID, targetUserID).First(&ban).Error; err != nil {
+		return errors.New("该用户未被封禁")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&ban).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "UNBAN_MEMBER", "USER", targetUserID, &roomID,
+			map[string]interface{}{"expiresAt": ban.ExpiresAt, "reason": ban.Reason},
+			nil,
+		)
+	})
+}
+
+// IsUserBanned 检查用户是否被聊天室封禁（已到期的封禁会被顺带清除）
+func (s *ChatRoomService) IsUserBanned(roomID, userID int64) bool {
+	var ban models.ChatRoomBan
+	if err := s.db.Where("chat_room_id = ? AND user_id = ?", roomID, userID).First(&ban).Error; err != nil {
+		return false
+	}
+
+	if ban.ExpiresAt != nil && !ban.ExpiresAt.After(time.Now()) {
+		s.db.Delete(&ban)
+		return false
+	}
+
+	return true
 }
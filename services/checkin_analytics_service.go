@@ -0,0 +1,336 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HeatmapDay 日历热力图中的一天
+type HeatmapDay struct {
+	Date          string `json:"date"`
+	CheckIns      int64  `json:"checkIns"`
+	TotalDuration int64  `json:"totalDuration"` // 学习总时长（分钟）
+}
+
+// TaskCompletion 打卡任务的完成率，应完成周期数从任务开始（或成员加入）所在周期算到当前周期（或任务结束）
+type TaskCompletion struct {
+	TaskID           int64   `json:"taskId"`
+	Title            string  `json:"title"`
+	Cycle            string  `json:"cycle"`
+	ExpectedPeriods  int64   `json:"expectedPeriods"`
+	CompletedPeriods int64   `json:"completedPeriods"`
+	MakeupPeriods    int64   `json:"makeupPeriods"` // 已完成周期中通过补卡完成的周期数
+	CompletionRate   float64 `json:"completionRate"`
+}
+
+// MissedCheckIn 某个打卡任务当前周期尚未打卡的成员
+type MissedCheckIn struct {
+	TaskID      int64         `json:"taskId"`
+	Title       string        `json:"title"`
+	Cycle       string        `json:"cycle"`
+	PeriodStart time.Time     `json:"periodStart"`
+	Users       []models.User `json:"users"`
+}
+
+// ParticipationDay 聊天室某一天的打卡参与情况
+type ParticipationDay struct {
+	Date              string  `json:"date"`
+	CheckIns          int64   `json:"checkIns"`
+	Participants      int64   `json:"participants"`
+	Members           int64   `json:"members"` // 当天已加入聊天室的成员数
+	ParticipationRate float64 `json:"participationRate"`
+}
+
+type CheckInAnalyticsService struct {
+	db              *gorm.DB
+	chatRoomService *ChatRoomService
+}
+
+func NewCheckInAnalyticsService() *CheckInAnalyticsService {
+	return &CheckInAnalyticsService{
+		db:              database.GetDB(),
+		chatRoomService: NewChatRoomService(),
+	}
+}
+
+// GetUserHeatmap 获取用户的打卡日历热力图，year为0时返回截至今天的最近一年
+func (s *CheckInAnalyticsService) GetUserHeatmap(roomID, userID int64, year int) ([]HeatmapDay, error) {
+	// 日期按聊天室时区计算，与打卡记录的check_date一致
+	loc := roomLocation(s.db, roomID)
+	var startDate, endDate time.Time
+	if year == 0 {
+		endDate = checkInDate(time.Now(), loc)
+		startDate = endDate.AddDate(-1, 0, 1)
+	} else {
+		startDate = checkInDate(time.Date(year, time.January, 1, 0, 0, 0, 0, loc), loc)
+		endDate = checkInDate(time.Date(year, time.December, 31, 0, 0, 0, 0, loc), loc)
+	}
+
+	type dayRow struct {
+		CheckDate     time.Time
+		CheckIns      int64
+		TotalDuration int64
+	}
+
+	var rows []dayRow
+	if err := s.db.Model(&models.CheckIn{}).
+		Select("check_date, COUNT(*) AS check_ins, COALESCE(SUM(duration_minutes), 0) AS total_duration").
+		Where("chat_room_id = ? AND user_id = ? AND check_date >= ? AND check_date <= ?", roomID, userID, startDate, endDate).
+		Group("check_date").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]dayRow, len(rows))
+	for _, row := range rows {
+		byDate[row.CheckDate.Format("2006-01-02")] = row
+	}
+
+	// 没有打卡的日期补0，方便前端直接渲染整年日历
+	days := make([]HeatmapDay, 0, 366)
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		key := date.Format("2006-01-02")
+		row := byDate[key]
+		days = append(days, HeatmapDay{Date: key, CheckIns: row.CheckIns, TotalDuration: row.TotalDuration})
+	}
+
+	return days, nil
+}
+
+// GetUserCompletion 获取用户在聊天室各打卡任务上的完成率，应完成周期从成员加入时算起
+func (s *CheckInAnalyticsService) GetUserCompletion(roomID, userID int64) ([]TaskCompletion, error) {
+	var member models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ? AND user_id = ?", roomID, userID).First(&member).Error; err != nil {
+		return nil, errors.New("用户不是该聊天室成员")
+	}
+
+	return s.getCompletion(roomID, []models.ChatRoomMember{member})
+}
+
+// GetRoomCompletion 获取聊天室各打卡任务的整体完成率（所有成员的已完成周期数之和 / 应完成周期数之和）
+func (s *CheckInAnalyticsService) GetRoomCompletion(roomID int64) ([]TaskCompletion, error) {
+	var members []models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ?", roomID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getCompletion(roomID, members)
+}
+
+// getCompletion 统计指定成员在聊天室各打卡任务上的完成情况
+func (s *CheckInAnalyticsService) getCompletion(roomID int64, members []models.ChatRoomMember) ([]TaskCompletion, error) {
+	var tasks []models.CheckInTask
+	if err := s.db.Where("chat_room_id = ?", roomID).Order("created_at ASC").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int64, len(members))
+	for i, member := range members {
+		userIDs[i] = member.UserID
+	}
+
+	// 一次查询取出每个成员在各任务上已完成的周期数
+	type completedRow struct {
+		TaskID        int64
+		UserID        int64
+		PeriodStart   time.Time
+		MakeupPeriods int64
+	}
+	var rows []completedRow
+	if len(userIDs) > 0 {
+		if err := s.db.Model(&models.CheckIn{}).
+			Select("task_id, user_id, period_start, MAX(is_makeup) AS makeup_periods").
+			Where("chat_room_id = ? AND user_id IN ? AND task_id IS NOT NULL AND period_start IS NOT NULL", roomID, userIDs).
+			Group("task_id, user_id, period_start").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 每个成员的应完成周期从任务开始和成员加入中较晚的那个周期算起
+	loc := roomLocation(s.db, roomID)
+	today := checkInDate(time.Now(), loc)
+	firstPeriods := make(map[int64]map[int64]time.Time, len(tasks))
+	for _, task := range tasks {
+		firstPeriods[task.ID] = make(map[int64]time.Time, len(members))
+		for _, member := range members {
+			start := task.StartDate
+			if joined := checkInDate(member.JoinedAt, loc); joined.After(start) {
+				start = joined
+			}
+			firstPeriods[task.ID][member.UserID] = cyclePeriodStart(task.Cycle, start)
+		}
+	}
+
+	completed := make(map[int64]int64)
+	makeups := make(map[int64]int64)
+	for _, row := range rows {
+		first, ok := firstPeriods[row.TaskID][row.UserID]
+		if !ok || row.PeriodStart.Before(first) {
+			continue
+		}
+		completed[row.TaskID]++
+		makeups[row.TaskID] += row.MakeupPeriods
+	}
+
+	completions := make([]TaskCompletion, 0, len(tasks))
+	for _, task := range tasks {
+		lastDate := today
+		if task.EndDate != nil && task.EndDate.Before(lastDate) {
+			lastDate = *task.EndDate
+		}
+		lastPeriod := cyclePeriodStart(task.Cycle, lastDate)
+
+		var expected int64
+		for _, first := range firstPeriods[task.ID] {
+			expected += countPeriods(task.Cycle, first, lastPeriod)
+		}
+
+		completion := TaskCompletion{
+			TaskID:           task.ID,
+			Title:            task.Title,
+			Cycle:            task.Cycle,
+			ExpectedPeriods:  expected,
+			CompletedPeriods: completed[task.ID],
+			MakeupPeriods:    makeups[task.ID],
+		}
+		if expected > 0 {
+			completion.CompletionRate = float64(completion.CompletedPeriods) / float64(expected)
+		}
+		completions = append(completions, completion)
+	}
+
+	return completions, nil
+}
+
+// countPeriods 计算两个周期（含首尾）之间的周期数
+func countPeriods(cycle string, first, last time.Time) int64 {
+	var count int64
+	for period := first; !period.After(last); period = nextPeriodStart(cycle, period) {
+		count++
+	}
+	return count
+}
+
+// GetMissedToday 获取各进行中的打卡任务当前周期尚未打卡的成员（房主和管理员可查看），指定taskID时只查询该任务
+func (s *CheckInAnalyticsService) GetMissedToday(roomID, operatorID int64, taskID *int64) ([]MissedCheckIn, error) {
+	if _, err := s.chatRoomService.checkManager(roomID, operatorID); err != nil {
+		return nil, err
+	}
+
+	query := s.db.Where("chat_room_id = ? AND is_active = ?", roomID, true)
+	if taskID != nil {
+		query = query.Where("id = ?", *taskID)
+	}
+	var tasks []models.CheckInTask
+	if err := query.Order("created_at ASC").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	today := checkInDate(time.Now(), roomLocation(s.db, roomID))
+	missed := make([]MissedCheckIn, 0, len(tasks))
+	for _, task := range tasks {
+		if today.Before(task.StartDate) || (task.EndDate != nil && today.After(*task.EndDate)) {
+			continue
+		}
+
+		periodStart := cyclePeriodStart(task.Cycle, today)
+		var members []models.ChatRoomMember
+		err := s.db.Preload("User").
+			Where("chat_room_id = ?", roomID).
+			Where("user_id NOT IN (?)", s.db.Model(&models.CheckIn{}).Select("user_id").
+				Where("task_id = ? AND period_start = ?", task.ID, periodStart)).
+			Order("joined_at ASC").
+			Find(&members).Error
+		if err != nil {
+			return nil, err
+		}
+
+		users := make([]models.User, len(members))
+		for i, member := range members {
+			users[i] = member.User
+		}
+		missed = append(missed, MissedCheckIn{
+			TaskID:      task.ID,
+			Title:       task.Title,
+			Cycle:       task.Cycle,
+			PeriodStart: periodStart,
+			Users:       users,
+		})
+	}
+
+	return missed, nil
+}
+
+// GetParticipationTrend 获取聊天室每天的打卡参与趋势，未指定日期范围时统计聊天室时区下的最近30天
+func (s *CheckInAnalyticsService) GetParticipationTrend(roomID int64, startDate, endDate *time.Time) ([]ParticipationDay, error) {
+	loc := roomLocation(s.db, roomID)
+	today := checkInDate(time.Now(), loc)
+	if endDate == nil {
+		endDate = &today
+	}
+	if startDate == nil {
+		defaultStart := endDate.AddDate(0, 0, -29)
+		startDate = &defaultStart
+	}
+	if startDate.After(*endDate) {
+		return nil, errors.New("开始日期不能晚于结束日期")
+	}
+	if endDate.Sub(*startDate) > 366*24*time.Hour {
+		return nil, errors.New("统计范围不能超过一年")
+	}
+
+	type dayRow struct {
+		CheckDate    time.Time
+		CheckIns     int64
+		Participants int64
+	}
+	var rows []dayRow
+	if err := s.db.Model(&models.CheckIn{}).
+		Select("check_date, COUNT(*) AS check_ins, COUNT(DISTINCT user_id) AS participants").
+		Where("chat_room_id = ? AND check_date >= ? AND check_date <= ?", roomID, *startDate, *endDate).
+		Group("check_date").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]dayRow, len(rows))
+	for _, row := range rows {
+		byDate[row.CheckDate.Format("2006-01-02")] = row
+	}
+
+	// 按加入日期统计每天的成员数（已退出的成员无法追溯，不计入）
+	var joinedAts []time.Time
+	if err := s.db.Model(&models.ChatRoomMember{}).
+		Where("chat_room_id = ?", roomID).
+		Order("joined_at ASC").
+		Pluck("joined_at", &joinedAts).Error; err != nil {
+		return nil, err
+	}
+
+	days := make([]ParticipationDay, 0)
+	joined := 0
+	for date := *startDate; !date.After(*endDate); date = date.AddDate(0, 0, 1) {
+		for joined < len(joinedAts) && !checkInDate(joinedAts[joined], loc).After(date) {
+			joined++
+		}
+
+		key := date.Format("2006-01-02")
+		row := byDate[key]
+		day := ParticipationDay{
+			Date:         key,
+			CheckIns:     row.CheckIns,
+			Participants: row.Participants,
+			Members:      int64(joined),
+		}
+		if day.Members > 0 {
+			day.ParticipationRate = float64(day.Participants) / float64(day.Members)
+		}
+		days = append(days, day)
+	}
+
+	return days, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CheckInExportFilter 导出打卡数据的过滤条件，与打卡记录和统计接口一致
+type CheckInExportFilter struct {
+	ChatRoomID int64
+	UserID     *int64
+	TaskID     *int64
+	StartDate  *time.Time
+	EndDate    *time.Time
+}
+
+// CheckInRecordRow 导出的一条打卡记录
+type CheckInRecordRow struct {
+	ID              int64
+	CheckDate       time.Time
+	UserID          int64
+	Username        string
+	TaskID          *int64
+	TaskTitle       *string
+	Content         string
+	DurationMinutes int
+	Location        string
+	IsMakeup        bool
+	LikeCount       int
+	CommentCount    int
+	CreatedAt       time.Time
+}
+
+// CheckInStatsRow 导出的一名成员的打卡统计
+type CheckInStatsRow struct {
+	UserID        int64
+	Username      string
+	CheckInCount  int64
+	MakeupCount   int64
+	TotalDuration int64
+	FirstCheck    time.Time
+	LastCheck     time.Time
+}
+
+type CheckInExportService struct {
+	db              *gorm.DB
+	chatRoomService *ChatRoomService
+}
+
+func NewCheckInExportService() *CheckInExportService {
+	return &CheckInExportService{
+		db:              database.GetDB(),
+		chatRoomService: NewChatRoomService(),
+	}
+}
+
+// CheckAccess 检查操作者是否可以导出聊天室的打卡数据（房主和管理员）
+func (s *CheckInExportService) CheckAccess(roomID, operatorID int64) error {
+	_, err := s.chatRoomService.checkManager(roomID, operatorID)
+	return err
+}
+
+// filterQuery 按过滤条件构造打卡记录查询
+func (s *CheckInExportService) filterQuery(filter CheckInExportFilter) *gorm.DB {
+	query := s.db.Table("checkin AS ci").Where("ci.chat_room_id = ?", filter.ChatRoomID)
+	if filter.UserID != nil {
+		query = query.Where("ci.user_id = ?", *filter.UserID)
+	}
+	if filter.TaskID != nil {
+		query = query.Where("ci.task_id = ?", *filter.TaskID)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("ci.check_date >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("ci.check_date <= ?", *filter.EndDate)
+	}
+	return query
+}
+
+// StreamRecords 逐行读取打卡记录并交给fn处理，不把全部记录加载到内存
+func (s *CheckInExportService) StreamRecords(filter CheckInExportFilter, fn func(row CheckInRecordRow) error) error {
+	rows, err := s.filterQuery(filter).
+		Select("ci.id, ci.check_date, ci.user_id, u.username, ci.task_id, t.title AS task_title, ci.content, " +
+			"ci.duration_minutes, ci.location, ci.is_makeup, ci.like_count, ci.comment_count, ci.created_at").
+		Joins("LEFT JOIN user AS u ON u.id = ci.user_id").
+		Joins("LEFT JOIN checkin_task AS t ON t.id = ci.task_id").
+		Order("ci.check_date ASC, ci.id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row CheckInRecordRow
+		if err := s.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamStats 在SQL中按成员聚合打卡统计，逐行交给fn处理，按打卡次数从多到少排列
+// 未指定日期范围时与打卡统计接口一致，统计聊天室时区下的最近30天
+func (s *CheckInExportService) StreamStats(filter CheckInExportFilter, fn func(row CheckInStatsRow) error) error {
+	today := checkInDate(time.Now(), roomLocation(s.db, filter.ChatRoomID))
+	if filter.EndDate == nil {
+		filter.EndDate = &today
+	}
+	if filter.StartDate == nil {
+		defaultStart := today.AddDate(0, 0, -30)
+		filter.StartDate = &defaultStart
+	}
+
+	rows, err := s.filterQuery(filter).
+		Select("ci.user_id, u.username, COUNT(*) AS check_in_count, COALESCE(SUM(ci.is_makeup), 0) AS makeup_count, " +
+			"COALESCE(SUM(ci.duration_minutes), 0) AS total_duration, MIN(ci.check_date) AS first_check, MAX(ci.check_date) AS last_check").
+		Joins("LEFT JOIN user AS u ON u.id = ci.user_id").
+		Group("ci.user_id, u.username").
+		Order("check_in_count DESC, ci.user_id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row CheckInStatsRow
+		if err := s.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CheckInFeedItem 打卡动态，附带当前用户是否已点赞
+type CheckInFeedItem struct {
+	models.CheckIn
+	LikedByMe bool `json:"likedByMe"`
+}
+
+type CheckInFeedService struct {
+	db              *gorm.DB
+	contentFilter   *ContentFilterService
+	chatRoomService *ChatRoomService
+}
+
+func NewCheckInFeedService() *CheckInFeedService {
+	return &CheckInFeedService{
+		db:              database.GetDB(),
+		contentFilter:   NewContentFilterService(),
+		chatRoomService: NewChatRoomService(),
+	}
+}
+
+// GetRoomFeed 获取聊天室的打卡动态（仅成员可查看），最新打卡排在前面
+func (s *CheckInFeedService) GetRoomFeed(roomID, viewerID int64, taskID *int64, page, pageSize int) ([]CheckInFeedItem, int64, error) {
+	if !s.isMember(roomID, viewerID) {
+		return nil, 0, errors.New("用户不是该聊天室成员")
+	}
+
+	query := s.db.Model(&models.CheckIn{}).Where("chat_room_id = ?", roomID)
+	if taskID != nil {
+		query = query.Where("task_id = ?", *taskID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var checkIns []models.CheckIn
+	offset := (page - 1) * pageSize
+	err := query.Preload("User").
+		Preload("Attachments", orderAttachments).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&checkIns).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 一次查询当前用户点赞过的打卡
+	checkInIDs := make([]int64, len(checkIns))
+	for i, checkIn := range checkIns {
+		checkInIDs[i] = checkIn.ID
+	}
+	var likedIDs []int64
+	if len(checkInIDs) > 0 {
+		if err := s.db.Model(&models.CheckInLike{}).
+			Where("user_id = ? AND check_in_id IN ?", viewerID, checkInIDs).
+			Pluck("check_in_id", &likedIDs).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+	liked := make(map[int64]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	items := make([]CheckInFeedItem, len(checkIns))
+	for i, checkIn := range checkIns {
+		items[i] = CheckInFeedItem{CheckIn: checkIn, LikedByMe: liked[checkIn.ID]}
+	}
+
+	return items, total, nil
+}
+
+// LikeCheckIn 点赞打卡，返回被点赞的打卡；重复点赞时liked为false
+func (s *CheckInFeedService) LikeCheckIn(checkInID, userID int64) (checkIn *models.CheckIn, liked bool, err error) {
+	if err := checkUserActive(s.db, userID); err != nil {
+		return nil, false, err
+	}
+
+	checkIn, err = s.getMemberCheckIn(checkInID, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.CheckInLike{
+			CheckInID: checkInID,
+			UserID:    userID,
+			CreatedAt: time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		liked = true
+		checkIn.LikeCount++
+		return tx.Model(&models.CheckIn{}).Where("id = ?", checkInID).
+			UpdateColumn("like_count", gorm.Expr("like_count + 1")).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return checkIn, liked, nil
+}
+
+// UnlikeCheckIn 取消点赞
+func (s *CheckInFeedService) UnlikeCheckIn(checkInID, userID int64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("check_in_id = ? AND user_id = ?", checkInID, userID).Delete(&models.CheckInLike{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("尚未点赞")
+		}
+
+		return tx.Model(&models.CheckIn{}).Where("id = ? AND like_count > 0", checkInID).
+			UpdateColumn("like_count", gorm.Expr("like_count - 1")).Error
+	})
+}
+
+// GetLikes 获取打卡的点赞列表（仅成员可查看）
+func (s *CheckInFeedService) GetLikes(checkInID, viewerID int64) ([]models.CheckInLike, error) {
+	if _, err := s.getMemberCheckIn(checkInID, viewerID); err != nil {
+		return nil, err
+	}
+
+	var likes []models.CheckInLike
+	err := s.db.Preload("User").
+		Where("check_in_id = ?", checkInID).
+		Order("created_at ASC").
+		Find(&likes).Error
+	return likes, err
+}
+
+// AddComment 评论打卡，返回评论和被评论的打卡
+func (s *CheckInFeedService) AddComment(checkInID, userID int64, content string) (*models.CheckInComment, *models.CheckIn, error) {
+	if err := checkUserActive(s.db, userID); err != nil {
+		return nil, nil, err
+	}
+
+	checkIn, err := s.getMemberCheckIn(checkInID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 敏感词过滤评论内容
+	content, err = s.contentFilter.Apply("CHECKIN", userID, &checkIn.ChatRoomID, content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comment := &models.CheckInComment{
+		CheckInID: checkInID,
+		UserID:    userID,
+		Content:   content,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+
+		checkIn.CommentCount++
+		return tx.Model(&models.CheckIn{}).Where("id = ?", checkInID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + 1")).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.db.Preload("User").First(comment, comment.ID)
+	return comment, checkIn, nil
+}
+
+// GetComments 分页获取打卡评论（仅成员可查看），按时间先后排列
+func (s *CheckInFeedService) GetComments(checkInID, viewerID int64, page, pageSize int) ([]models.CheckInComment, int64, error) {
+	if _, err := s.getMemberCheckIn(checkInID, viewerID); err != nil {
+		return nil, 0, err
+	}
+
+	query := s.db.Model(&models.CheckInComment{}).Where("check_in_id = ?", checkInID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var comments []models.CheckInComment
+	offset := (page - 1) * pageSize
+	err := query.Preload("User").
+		Order("created_at ASC, id ASC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&comments).Error
+
+	return comments, total, err
+}
+
+// DeleteComment 删除评论，评论者本人或房主、管理员可删除，删除他人评论时记录审计日志
+func (s *CheckInFeedService) DeleteComment(commentID, operatorID int64) error {
+	var comment models.CheckInComment
+	if err := s.db.First(&comment, commentID).Error; err != nil {
+		return errors.New("评论不存在")
+	}
+
+	var checkIn models.CheckIn
+	if err := s.db.First(&checkIn, comment.CheckInID).Error; err != nil {
+		return errors.New("打卡记录不存在")
+	}
+
+	if comment.UserID != operatorID {
+		if _, err := s.chatRoomService.checkManager(checkIn.ChatRoomID, operatorID); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.CheckInComment{}, comment.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("评论不存在")
+		}
+
+		if err := tx.Model(&models.CheckIn{}).Where("id = ? AND comment_count > 0", checkIn.ID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count - 1")).Error; err != nil {
+			return err
+		}
+
+		if comment.UserID == operatorID {
+			return nil
+		}
+		return writeAuditLog(tx, operatorID, "DELETE_CHECKIN_COMMENT", "CHECKIN_COMMENT", comment.ID, &checkIn.ChatRoomID, comment, nil)
+	})
+}
+
+// getMemberCheckIn 获取打卡记录，并检查用户是否是打卡所在聊天室的成员
+func (s *CheckInFeedService) getMemberCheckIn(checkInID, userID int64) (*models.CheckIn, error) {
+	var checkIn models.CheckIn
+	if err := s.db.First(&checkIn, checkInID).Error; err != nil {
+		return nil, errors.New("打卡记录不存在")
+	}
+
+	if !s.isMember(checkIn.ChatRoomID, userID) {
+		return nil, errors.New("用户不是该聊天室成员")
+	}
+
+	return &checkIn, nil
+}
+
+// isMember 检查用户是否是聊天室成员
+func (s *CheckInFeedService) isMember(roomID, userID int64) bool {
+	var count int64
+	s.db.Model(&models.ChatRoomMember{}).Where("chat_room_id = ? AND user_id = ?", roomID, userID).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CheckInMakeupService struct {
+	db              *gorm.DB
+	contentFilter   *ContentFilterService
+	chatRoomService *ChatRoomService
+}
+
+func NewCheckInMakeupService() *CheckInMakeupService {
+	return &CheckInMakeupService{
+		db:              database.GetDB(),
+		contentFilter:   NewContentFilterService(),
+		chatRoomService: NewChatRoomService(),
+	}
+}
+
+// SubmitMakeup 提交补卡申请，任务无需审批时直接生成补卡记录
+func (s *CheckInMakeupService) SubmitMakeup(makeup *models.CheckInMakeup) error {
+	if err := checkUserActive(s.db, makeup.UserID); err != nil {
+		return err
+	}
+
+	// 检查用户是否是聊天室成员
+	var member models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ? AND user_id = ?", makeup.ChatRoomID, makeup.UserID).First(&member).Error; err != nil {
+		return errors.New("用户不是该聊天室成员")
+	}
+
+	// 检查打卡任务是否允许补卡
+	var task models.CheckInTask
+	if err := s.db.First(&task, makeup.TaskID).Error; err != nil || task.ChatRoomID != makeup.ChatRoomID {
+		return errors.New("打卡任务不存在")
+	}
+	if !task.IsActive {
+		return errors.New("打卡任务已停用")
+	}
+	if task.MakeupWindowDays <= 0 {
+		return errors.New("该打卡任务未开启补卡")
+	}
+
+	// 补卡日期需在补卡窗口内，且不早于任务开始日期、不晚于任务结束日期
+	loc := roomLocation(s.db, makeup.ChatRoomID)
+	today := checkInDate(time.Now(), loc)
+	if !makeup.CheckDate.Before(today) {
+		return errors.New("只能补今天之前的卡")
+	}
+	windowStart := today.AddDate(0, 0, -task.MakeupWindowDays)
+	if makeup.CheckDate.Before(windowStart) {
+		return errors.New("超出可补卡的时间范围")
+	}
+	if makeup.CheckDate.Before(task.StartDate) || (task.EndDate != nil && makeup.CheckDate.After(*task.EndDate)) {
+		return errors.New("补卡日期不在打卡任务的有效期内")
+	}
+
+	// 当前周期仍可正常打卡，不需要补卡
+	currentPeriod := cyclePeriodStart(task.Cycle, today)
+	makeup.PeriodStart = cyclePeriodStart(task.Cycle, makeup.CheckDate)
+	if sameDate(makeup.PeriodStart, currentPeriod) {
+		return errors.New("当前周期可直接打卡，无需补卡")
+	}
+
+	// 敏感词过滤补卡内容
+	content, err := s.contentFilter.Apply("CHECKIN", makeup.UserID, &makeup.ChatRoomID, makeup.Content)
+	if err != nil {
+		return err
+	}
+	makeup.Content = content
+
+	makeup.Status = "PENDING"
+	makeup.CreatedAt = time.Now()
+	makeup.UpdatedAt = time.Now()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定成员记录，同一用户的补卡申请依次检查和写入，避免并发提交超出次数限制
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("chat_room_id = ? AND user_id = ?", makeup.ChatRoomID, makeup.UserID).
+			First(&models.ChatRoomMember{}).Error; err != nil {
+			return errors.New("用户不是该聊天室成员")
+		}
+
+		if err := s.checkMakeupPeriod(tx, task.ID, makeup.UserID, makeup.PeriodStart); err != nil {
+			return err
+		}
+
+		// 补卡次数按补卡日期所在的周期统计，与提交时间无关
+		if task.MakeupLimit > 0 {
+			var count int64
+			if err := tx.Model(&models.CheckInMakeup{}).
+				Where("task_id = ? AND user_id = ? AND status IN ? AND period_start >= ? AND period_start < ?",
+					task.ID, makeup.UserID, []string{"PENDING", "APPROVED"},
+					makeup.PeriodStart, nextPeriodStart(task.Cycle, makeup.PeriodStart)).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= int64(task.MakeupLimit) {
+				return errors.New("本周期补卡次数已用完")
+			}
+		}
+
+		if err := tx.Create(makeup).Error; err != nil {
+			return err
+		}
+
+		// 无需审批的任务直接通过
+		if task.MakeupRequiresApproval {
+			return nil
+		}
+		return approveMakeup(tx, makeup, nil, "")
+	})
+}
+
+// GetRoomMakeups 获取聊天室的补卡申请（房主和管理员可查看）
+func (s *CheckInMakeupService) GetRoomMakeups(roomID, operatorID int64, status string, page, pageSize int) ([]models.CheckInMakeup, int64, error) {
+	if _, err := s.chatRoomService.checkManager(roomID, operatorID); err != nil {
+		return nil, 0, err
+	}
+
+	query := s.db.Model(&models.CheckInMakeup{}).Where("chat_room_id = ?", roomID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	return s.findMakeups(query, page, pageSize)
+}
+
+// GetUserMakeups 获取用户在聊天室提交的补卡申请及审批进度
+func (s *CheckInMakeupService) GetUserMakeups(roomID, userID int64, page, pageSize int) ([]models.CheckInMakeup, int64, error) {
+	query := s.db.Model(&models.CheckInMakeup{}).Where("chat_room_id = ? AND user_id = ?", roomID, userID)
+	return s.findMakeups(query, page, pageSize)
+}
+
+// findMakeups 分页查询补卡申请，最新提交的排在前面
+func (s *CheckInMakeupService) findMakeups(query *gorm.DB, page, pageSize int) ([]models.CheckInMakeup, int64, error) {
+	var makeups []models.CheckInMakeup
+	var total int64
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := query.Preload("User").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&makeups).Error
+
+	return makeups, total, err
+}
+
+// ReviewMakeup 房主或管理员审批补卡申请，通过后生成补卡记录
+func (s *CheckInMakeupService) ReviewMakeup(makeupID, operatorID int64, approve bool, note string) (*models.CheckInMakeup, error) {
+	var makeup models.CheckInMakeup
+	if err := s.db.First(&makeup, makeupID).Error; err != nil {
+		return nil, errors.New("补卡申请不存在")
+	}
+
+	if _, err := s.chatRoomService.checkManager(makeup.ChatRoomID, operatorID); err != nil {
+		return nil, err
+	}
+
+	if makeup.Status != "PENDING" {
+		return nil, errors.New("补卡申请已处理")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if approve {
+			if err := s.checkMakeupCheckedIn(tx, makeup.TaskID, makeup.UserID, makeup.PeriodStart); err != nil {
+				return err
+			}
+			if err := approveMakeup(tx, &makeup, &operatorID, note); err != nil {
+				return err
+			}
+		} else {
+			now := time.Now()
+			result := tx.Model(&models.CheckInMakeup{}).
+				Where("id = ? AND status = ?", makeup.ID, "PENDING").
+				Updates(map[string]interface{}{
+					"status":      "REJECTED",
+					"reviewer_id": operatorID,
+					"reviewed_at": now,
+					"review_note": note,
+					"updated_at":  now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("补卡申请已处理")
+			}
+			makeup.Status = "REJECTED"
+			makeup.ReviewerID = &operatorID
+			makeup.ReviewedAt = &now
+			makeup.ReviewNote = note
+		}
+
+		return writeAuditLog(tx, operatorID, "REVIEW_CHECKIN_MAKEUP", "CHECKIN_MAKEUP", makeup.ID, &makeup.ChatRoomID,
+			map[string]interface{}{"status": "PENDING"},
+			map[string]interface{}{"status": makeup.Status, "note": note},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &makeup, nil
+}
+
+// checkMakeupPeriod 检查补卡周期内是否已打卡或已有待审批的补卡申请
+func (s *CheckInMakeupService) checkMakeupPeriod(db *gorm.DB, taskID, userID int64, periodStart time.Time) error {
+	if err := s.checkMakeupCheckedIn(db, taskID, userID, periodStart); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&models.CheckInMakeup{}).
+		Where("task_id = ? AND user_id = ? AND period_start = ? AND status = ?", taskID, userID, periodStart, "PENDING").
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("该周期已有待审批的补卡申请")
+	}
+
+	return nil
+}
+
+// checkMakeupCheckedIn 检查补卡周期内是否已有打卡记录
+func (s *CheckInMakeupService) checkMakeupCheckedIn(db *gorm.DB, taskID, userID int64, periodStart time.Time) error {
+	var count int64
+	if err := db.Model(&models.CheckIn{}).
+		Where("task_id = ? AND user_id = ? AND period_start = ?", taskID, userID, periodStart).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("该周期已经打卡过了")
+	}
+	return nil
+}
+
+// approveMakeup 通过补卡申请并生成补卡记录，reviewerID为空表示自动通过
+func approveMakeup(tx *gorm.DB, makeup *models.CheckInMakeup, reviewerID *int64, note string) error {
+	periodStart := makeup.PeriodStart
+	checkIn := &models.CheckIn{
+		ChatRoomID:  makeup.ChatRoomID,
+		TaskID:      &makeup.TaskID,
+		UserID:      makeup.UserID,
+		Content:     makeup.Content,
+		CheckDate:   makeup.CheckDate,
+		PeriodStart: &periodStart,
+		IsMakeup:    true,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := tx.Create(checkIn).Error; err != nil {
+		// 唯一索引冲突说明该周期在此期间已打卡，其他错误原样返回
+		if isDuplicateKeyError(err) {
+			return errors.New("该周期已经打卡过了")
+		}
+		return err
+	}
+
+	now := time.Now()
+	result := tx.Model(&models.CheckInMakeup{}).
+		Where("id = ? AND status = ?", makeup.ID, "PENDING").
+		Updates(map[string]interface{}{
+			"status":      "APPROVED",
+			"reviewer_id": reviewerID,
+			"reviewed_at": now,
+			"review_note": note,
+			"check_in_id": checkIn.ID,
+			"updated_at":  now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("补卡申请已处理")
+	}
+
+	makeup.Status = "APPROVED"
+	makeup.ReviewerID = reviewerID
+	makeup.ReviewedAt = &now
+	makeup.ReviewNote = note
+	makeup.CheckInID = &checkIn.ID
+	return nil
+}
+
+// isDuplicateKeyError 判断是否为MySQL唯一索引冲突错误
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
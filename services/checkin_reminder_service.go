@@ -0,0 +1,230 @@
+package services
+
+import (
+	"campus-canvas-chat/config"
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	campusredis "campus-canvas-chat/redis"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// reminderMarkTTL 提醒和总结发送标记的保留时间，防止同一周期重复发送
+const reminderMarkTTL = 48 * time.Hour
+
+// CheckInNotifier 打卡提醒和每日总结的推送方式（由WebSocket Hub实现）
+type CheckInNotifier interface {
+	// DeliverToUser 推送给在线用户，不在线时存为离线消息
+	DeliverToUser(userID int64, message []byte)
+	BroadcastToRoom(roomID int64, message []byte)
+}
+
+type CheckInReminderService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	notifier    CheckInNotifier
+}
+
+func NewCheckInReminderService(notifier CheckInNotifier) *CheckInReminderService {
+	return &CheckInReminderService{
+		db:          database.GetDB(),
+		redisClient: campusredis.GetClient(),
+		notifier:    notifier,
+	}
+}
+
+// SendDueReminders 在打卡周期最后一天到达提醒时间后，提醒本周期尚未打卡的成员（每个任务每个周期只提醒一次）
+func (s *CheckInReminderService) SendDueReminders(now time.Time) error {
+	var tasks []models.CheckInTask
+	err := s.db.Joins("JOIN chatroom ON chatroom.id = checkin_task.chat_room_id AND chatroom.is_active = ? AND chatroom.deleted_at IS NULL", true).
+		Where("checkin_task.is_active = ? AND checkin_task.reminder_time <> ''", true).
+		Find(&tasks).Error
+	if err != nil {
+		return err
+	}
+
+	locations := make(map[int64]*time.Location)
+	for _, task := range tasks {
+		loc, ok := locations[task.ChatRoomID]
+		if !ok {
+			loc = roomLocation(s.db, task.ChatRoomID)
+			locations[task.ChatRoomID] = loc
+		}
+
+		today := checkInDate(now, loc)
+		if today.Before(task.StartDate) || (task.EndDate != nil && today.After(*task.EndDate)) {
+			continue
+		}
+
+		// 只在周期最后一天的提醒时间之后提醒
+		periodStart := cyclePeriodStart(task.Cycle, today)
+		deadline := nextPeriodStart(task.Cycle, periodStart)
+		if !sameDate(deadline.AddDate(0, 0, -1), today) || now.In(loc).Format("15:04") < task.ReminderTime {
+			continue
+		}
+
+		key := fmt.Sprintf("checkin:reminder:%d:%s", task.ID, periodStart.Format("2006-01-02"))
+		if !s.markSent(key) {
+			continue
+		}
+
+		if err := s.remindTask(task, periodStart, deadline); err != nil {
+			s.unmarkSent(key)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remindTask 向任务所在聊天室中本周期未打卡且未关闭提醒的成员发送提醒
+func (s *CheckInReminderService) remindTask(task models.CheckInTask, periodStart, deadline time.Time) error {
+	var userIDs []int64
+	err := s.db.Model(&models.ChatRoomMember{}).
+		Where("chat_room_id = ? AND check_in_reminders_off = ?", task.ChatRoomID, false).
+		Where("user_id NOT IN (?)", s.db.Model(&models.CheckIn{}).Select("user_id").
+			Where("task_id = ? AND period_start = ?", task.ID, periodStart)).
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return err
+	}
+
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":        "checkin_reminder",
+		"roomId":      task.ChatRoomID,
+		"taskId":      task.ID,
+		"title":       task.Title,
+		"cycle":       task.Cycle,
+		"periodStart": periodStart.Format("2006-01-02"),
+		"deadline":    deadline.Format("2006-01-02"),
+	})
+	for _, userID := range userIDs {
+		s.notifier.DeliverToUser(userID, messageData)
+	}
+
+	return nil
+}
+
+// PostDailySummaries 每天到达总结时间后，向有进行中打卡任务的聊天室发送当天的打卡总结（每个聊天室每天只发送一次）
+func (s *CheckInReminderService) PostDailySummaries(now time.Time) error {
+	summaryTime := config.GetConfig().Campus.CheckInSummaryTime
+	if summaryTime == "" {
+		return nil
+	}
+
+	var tasks []models.CheckInTask
+	err := s.db.Joins("JOIN chatroom ON chatroom.id = checkin_task.chat_room_id AND chatroom.is_active = ? AND chatroom.deleted_at IS NULL", true).
+		Where("checkin_task.is_active = ?", true).
+		Order("checkin_task.chat_room_id, checkin_task.id").
+		Find(&tasks).Error
+	if err != nil {
+		return err
+	}
+
+	roomTasks := make(map[int64][]models.CheckInTask)
+	var roomIDs []int64
+	for _, task := range tasks {
+		if _, ok := roomTasks[task.ChatRoomID]; !ok {
+			roomIDs = append(roomIDs, task.ChatRoomID)
+		}
+		roomTasks[task.ChatRoomID] = append(roomTasks[task.ChatRoomID], task)
+	}
+
+	for _, roomID := range roomIDs {
+		loc := roomLocation(s.db, roomID)
+		if now.In(loc).Format("15:04") < summaryTime {
+			continue
+		}
+
+		today := checkInDate(now, loc)
+		key := fmt.Sprintf("checkin:summary:%d:%s", roomID, today.Format("2006-01-02"))
+		if !s.markSent(key) {
+			continue
+		}
+
+		if err := s.postRoomSummary(roomID, roomTasks[roomID], today); err != nil {
+			s.unmarkSent(key)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// postRoomSummary 统计聊天室各任务本周期的打卡人数，以系统消息（UserID为0）发送到聊天室
+func (s *CheckInReminderService) postRoomSummary(roomID int64, tasks []models.CheckInTask, today time.Time) error {
+	var memberCount int64
+	if err := s.db.Model(&models.ChatRoomMember{}).Where("chat_room_id = ?", roomID).Count(&memberCount).Error; err != nil {
+		return err
+	}
+
+	lines := []string{fmt.Sprintf("📅 今日打卡总结（%s）", today.Format("2006-01-02"))}
+	for _, task := range tasks {
+		if today.Before(task.StartDate) || (task.EndDate != nil && today.After(*task.EndDate)) {
+			continue
+		}
+
+		var checkedIn int64
+		periodStart := cyclePeriodStart(task.Cycle, today)
+		if err := s.db.Model(&models.CheckIn{}).
+			Where("task_id = ? AND period_start = ?", task.ID, periodStart).
+			Count(&checkedIn).Error; err != nil {
+			return err
+		}
+
+		lines = append(lines, fmt.Sprintf("%s（%s）：%d/%d 人已打卡", task.Title, cycleName(task.Cycle), checkedIn, memberCount))
+	}
+	if len(lines) == 1 {
+		return nil
+	}
+
+	message := &models.Message{
+		ChatRoomID: roomID,
+		UserID:     0,
+		Content:    strings.Join(lines, "\n"),
+		CreatedAt:  time.Now(),
+	}
+	if err := s.db.Create(message).Error; err != nil {
+		return err
+	}
+
+	messageData, _ := json.Marshal(map[string]interface{}{
+		"type":    "checkin_summary",
+		"message": message,
+	})
+	s.notifier.BroadcastToRoom(roomID, messageData)
+
+	return nil
+}
+
+// markSent 发送前标记提醒或总结已发送，已被其他实例或之前的检查标记过时返回false，发送失败需调用unmarkSent清除
+func (s *CheckInReminderService) markSent(key string) bool {
+	ok, err := s.redisClient.SetNX(context.Background(), key, 1, reminderMarkTTL).Result()
+	return err == nil && ok
+}
+
+// unmarkSent 发送失败时清除发送标记，下一轮定时检查时重试
+func (s *CheckInReminderService) unmarkSent(key string) {
+	if err := s.redisClient.Del(context.Background(), key).Err(); err != nil {
+		log.Printf("清除打卡提醒标记失败: %v", err)
+	}
+}
+
+// cycleName 打卡周期的中文名称
+func cycleName(cycle string) string {
+	switch cycle {
+	case "WEEKLY":
+		return "本周"
+	case "MONTHLY":
+		return "本月"
+	default:
+		return "今日"
+	}
+}
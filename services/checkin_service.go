@@ -1,21 +1,26 @@
 package services
 
 import (
+	"campus-canvas-chat/config"
 	"campus-canvas-chat/database"
 	"campus-canvas-chat/models"
 	"errors"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CheckInService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	contentFilter *ContentFilterService
 }
 
 func NewCheckInService() *CheckInService {
 	return &CheckInService{
-		db: database.GetDB(),
+		db:            database.GetDB(),
+		contentFilter: NewContentFilterService(),
 	}
 }
 
@@ -32,7 +37,13 @@ func (s *CheckInService) CreateCheckInTask(task *models.CheckInTask, operatorID
 	}
 
 	// 创建打卡任务
-	return s.db.Create(task).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "CREATE_CHECKIN_TASK", "CHECKIN_TASK", task.ID, &task.ChatRoomID, nil, task)
+	})
 }
 
 // GetCheckInTasks 获取聊天室的打卡任务列表
@@ -67,7 +78,14 @@ func (s *CheckInService) UpdateCheckInTask(taskID int64, updates map[string]inte
 	}
 
 	// 更新任务
-	return s.db.Model(&task).Updates(updates).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		before := task
+		if err := tx.Model(&task).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "UPDATE_CHECKIN_TASK", "CHECKIN_TASK", taskID, &task.ChatRoomID, before, task)
+	})
 }
 
 // DeleteCheckInTask 删除打卡任务
@@ -89,36 +107,149 @@ func (s *CheckInService) DeleteCheckInTask(taskID int64, operatorID int64) error
 	}
 
 	// 软删除任务
-	return s.db.Model(&task).Update("is_active", false).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&task).Update("is_active", false).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "DELETE_CHECKIN_TASK", "CHECKIN_TASK", taskID, &task.ChatRoomID,
+			map[string]interface{}{"isActive": true},
+			map[string]interface{}{"isActive": false},
+		)
+	})
 }
 
-// SubmitCheckIn 提交打卡记录
+// SubmitCheckIn 提交打卡记录，每个任务每个周期只能打卡一次
 func (s *CheckInService) SubmitCheckIn(checkIn *models.CheckIn) error {
+	if err := checkUserActive(s.db, checkIn.UserID); err != nil {
+		return err
+	}
+
 	// 检查用户是否是聊天室成员
 	var member models.ChatRoomMember
 	if err := s.db.Where("chat_room_id = ? AND user_id = ?", checkIn.ChatRoomID, checkIn.UserID).First(&member).Error; err != nil {
 		return errors.New("用户不是该聊天室成员")
 	}
 
-	// 检查今天是否已经打卡
-	today := time.Now().Format("2006-01-02")
-	checkDate, _ := time.Parse("2006-01-02", today)
+	// 检查打卡任务是否可以打卡
+	var task models.CheckInTask
+	if checkIn.TaskID == nil || s.db.First(&task, *checkIn.TaskID).Error != nil || task.ChatRoomID != checkIn.ChatRoomID {
+		return errors.New("打卡任务不存在")
+	}
+	if !task.IsActive {
+		return errors.New("打卡任务已停用")
+	}
+
+	checkDate := s.roomToday(checkIn.ChatRoomID)
+	if checkDate.Before(task.StartDate) {
+		return errors.New("打卡任务尚未开始")
+	}
+	if task.EndDate != nil && checkDate.After(*task.EndDate) {
+		return errors.New("打卡任务已结束")
+	}
+
+	// 检查本周期是否已经打卡
+	periodStart := cyclePeriodStart(task.Cycle, checkDate)
+	if s.hasCheckedIn(task.ID, checkIn.UserID, periodStart) {
+		return errors.New("本周期已经打卡过了")
+	}
+
+	// 敏感词过滤打卡内容
+	content, err := s.contentFilter.Apply("CHECKIN", checkIn.UserID, &checkIn.ChatRoomID, checkIn.Content)
+	if err != nil {
+		return err
+	}
+	checkIn.Content = content
+
+	location, err := s.contentFilter.Apply("CHECKIN", checkIn.UserID, &checkIn.ChatRoomID, checkIn.Location)
+	if err != nil {
+		return err
+	}
+	checkIn.Location = location
 
-	var existingCheckIn models.CheckIn
-	if err := s.db.Where("chat_room_id = ? AND user_id = ? AND check_date = ?",
-		checkIn.ChatRoomID, checkIn.UserID, checkDate).First(&existingCheckIn).Error; err == nil {
-		return errors.New("今天已经打卡过了")
+	for i := range checkIn.Attachments {
+		checkIn.Attachments[i].SortOrder = i
+		checkIn.Attachments[i].CreatedAt = time.Now()
 	}
 
-	// 设置打卡日期为今天
+	// 设置打卡日期为今天，周期为今天所在的任务周期
 	checkIn.CheckDate = checkDate
+	checkIn.PeriodStart = &periodStart
+
+	// 提交打卡记录（附件随记录一起保存），并发提交时由唯一索引保证每周期只有一条
+	// 锁定聊天室记录，同一聊天室的打卡依次写入并提交，打卡ID较小的记录一定已经提交（供"抢到首卡"徽章判断先后）
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.ChatRoom{}, checkIn.ChatRoomID).Error; err != nil {
+			return errors.New("聊天室不存在")
+		}
+		return tx.Create(checkIn).Error
+	})
+	if err != nil {
+		if s.hasCheckedIn(task.ID, checkIn.UserID, periodStart) {
+			return errors.New("本周期已经打卡过了")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// hasCheckedIn 检查用户在任务的指定周期内是否已打卡
+func (s *CheckInService) hasCheckedIn(taskID, userID int64, periodStart time.Time) bool {
+	var count int64
+	s.db.Model(&models.CheckIn{}).
+		Where("task_id = ? AND user_id = ? AND period_start = ?", taskID, userID, periodStart).
+		Count(&count)
+	return count > 0
+}
+
+// roomToday 获取聊天室时区下的当天打卡日期
+func (s *CheckInService) roomToday(chatRoomID int64) time.Time {
+	return checkInDate(time.Now(), roomLocation(s.db, chatRoomID))
+}
+
+// roomLocation 获取聊天室打卡使用的时区，未设置时使用校园默认时区
+func roomLocation(db *gorm.DB, chatRoomID int64) *time.Location {
+	var timezone string
+	db.Model(&models.ChatRoom{}).Where("id = ?", chatRoomID).Pluck("timezone", &timezone)
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			return loc
+		}
+	}
+	return campusLocation()
+}
+
+// campusLocation 获取校园默认时区，配置无效时使用服务器本地时区
+func campusLocation() *time.Location {
+	if loc, err := time.LoadLocation(config.GetConfig().Campus.Timezone); err == nil {
+		return loc
+	}
+	return time.Local
+}
 
-	// 提交打卡记录
-	return s.db.Create(checkIn).Error
+// checkInDate 计算时间点在指定时区下的日期
+// 返回值以服务器本地时区的零点表示，与数据库连接（loc=Local）读写DATE字段的方式一致
+func checkInDate(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
 }
 
-// GetCheckInRecords 获取打卡记录
-func (s *CheckInService) GetCheckInRecords(chatRoomID int64, userID *int64, startDate, endDate *time.Time, page, pageSize int) ([]models.CheckIn, int64, error) {
+// cyclePeriodStart 计算日期所在打卡周期的第一天（每周从周一开始）
+func cyclePeriodStart(cycle string, date time.Time) time.Time {
+	switch cycle {
+	case "WEEKLY":
+		offset := (int(date.Weekday()) + 6) % 7
+		return date.AddDate(0, 0, -offset)
+	case "MONTHLY":
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	default:
+		return date
+	}
+}
+
+// GetCheckInRecords 获取打卡记录，可按用户、任务和日期范围过滤
+func (s *CheckInService) GetCheckInRecords(chatRoomID int64, userID, taskID *int64, startDate, endDate *time.Time, page, pageSize int) ([]models.CheckIn, int64, error) {
 	var checkIns []models.CheckIn
 	var total int64
 
@@ -128,6 +259,10 @@ func (s *CheckInService) GetCheckInRecords(chatRoomID int64, userID *int64, star
 		query = query.Where("user_id = ?", *userID)
 	}
 
+	if taskID != nil {
+		query = query.Where("task_id = ?", *taskID)
+	}
+
 	if startDate != nil {
 		query = query.Where("check_date >= ?", *startDate)
 	}
@@ -144,6 +279,7 @@ func (s *CheckInService) GetCheckInRecords(chatRoomID int64, userID *int64, star
 	// 分页查询
 	offset := (page - 1) * pageSize
 	err := query.Preload("User").
+		Preload("Attachments", orderAttachments).
 		Order("check_date DESC, created_at DESC").
 		Offset(offset).
 		Limit(pageSize).
@@ -152,123 +288,410 @@ func (s *CheckInService) GetCheckInRecords(chatRoomID int64, userID *int64, star
 	return checkIns, total, err
 }
 
-// GetCheckInStats 获取打卡统计信息
-func (s *CheckInService) GetCheckInStats(chatRoomID int64, startDate, endDate time.Time) (map[string]interface{}, error) {
-	// 获取时间范围内的打卡记录
-	var checkIns []models.CheckIn
-	err := s.db.Where("chat_room_id = ? AND check_date >= ? AND check_date <= ?",
-		chatRoomID, startDate, endDate).Preload("User").Find(&checkIns).Error
+// orderAttachments 按上传顺序加载打卡附件
+func orderAttachments(db *gorm.DB) *gorm.DB {
+	return db.Order("sort_order ASC")
+}
+
+// GetCheckInStats 获取打卡统计信息，指定taskID时只统计该任务；未指定日期范围时统计聊天室时区下的最近30天
+func (s *CheckInService) GetCheckInStats(chatRoomID int64, taskID *int64, startDate, endDate *time.Time) (map[string]interface{}, error) {
+	today := s.roomToday(chatRoomID)
+	if endDate == nil {
+		endDate = &today
+	}
+	if startDate == nil {
+		defaultStart := today.AddDate(0, 0, -30)
+		startDate = &defaultStart
+	}
+
+	// 总体统计
+	var totals struct {
+		TotalCheckIns int64
+		UniqueUsers   int64
+	}
+	if err := s.rangeQuery(chatRoomID, taskID, *startDate, *endDate).
+		Select("COUNT(*) AS total_check_ins, COUNT(DISTINCT user_id) AS unique_users").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	// 打卡次数排行榜前20名
+	leaderboard, err := s.GetCheckInLeaderboard(chatRoomID, taskID, "COUNT", startDate, endDate, 1, 20, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// 统计每个用户的打卡次数
-	userStats := make(map[int64]map[string]interface{})
-	for _, checkIn := range checkIns {
-		if userStats[checkIn.UserID] == nil {
-			userStats[checkIn.UserID] = map[string]interface{}{
-				"user":       checkIn.User,
-				"count":      0,
-				"last_check": checkIn.CheckDate,
-			}
+	// 计算每个用户在各任务上的连续打卡周期数
+	streaks, err := s.GetCheckInStreaks(chatRoomID, nil, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"total_check_ins": totals.TotalCheckIns,
+		"unique_users":    totals.UniqueUsers,
+		"ranking":         leaderboard.Entries,
+		"streaks":         streaks,
+	}, nil
+}
+
+// rangeQuery 聊天室在日期范围内的打卡记录查询，指定taskID时只查询该任务
+func (s *CheckInService) rangeQuery(chatRoomID int64, taskID *int64, startDate, endDate time.Time) *gorm.DB {
+	query := s.db.Model(&models.CheckIn{}).
+		Where("chat_room_id = ? AND check_date >= ? AND check_date <= ?", chatRoomID, startDate, endDate)
+	if taskID != nil {
+		query = query.Where("task_id = ?", *taskID)
+	}
+	return query
+}
+
+// LeaderboardEntry 打卡排行榜中的一名成员，得分相同的成员排名相同（密集排名）
+type LeaderboardEntry struct {
+	Rank          int         `json:"rank"`
+	User          models.User `json:"user"`
+	CheckInCount  int64       `json:"checkInCount"`
+	TotalDuration int64       `json:"totalDuration"` // 学习总时长（分钟）
+	CurrentStreak int         `json:"currentStreak"` // 仅按连续打卡排名时返回
+	LastCheckDate *time.Time  `json:"lastCheckDate,omitempty"`
+}
+
+// CheckInLeaderboard 打卡排行榜的一页，Me为查询者本人的排名（未上榜时为空）
+type CheckInLeaderboard struct {
+	Mode     string             `json:"mode"`
+	Entries  []LeaderboardEntry `json:"entries"`
+	Total    int64              `json:"total"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"pageSize"`
+	Me       *LeaderboardEntry  `json:"me"`
+}
+
+// leaderboardScores 各排名方式在SQL中的得分表达式
+var leaderboardScores = map[string]string{
+	"COUNT":    "COUNT(*)",
+	"DURATION": "COALESCE(SUM(duration_minutes), 0)",
+}
+
+// GetCheckInLeaderboard 获取打卡排行榜，mode为COUNT（打卡次数）、DURATION（学习总时长）或STREAK（当前连续打卡，需指定任务）
+// 得分相同时排名相同，同名次内按用户ID排序；viewerID不为空时同时返回其本人排名
+func (s *CheckInService) GetCheckInLeaderboard(chatRoomID int64, taskID *int64, mode string, startDate, endDate *time.Time, page, pageSize int, viewerID *int64) (*CheckInLeaderboard, error) {
+	if mode == "STREAK" {
+		return s.getStreakLeaderboard(chatRoomID, taskID, page, pageSize, viewerID)
+	}
+
+	score, ok := leaderboardScores[mode]
+	if !ok {
+		return nil, errors.New("无效的排名方式")
+	}
+
+	today := s.roomToday(chatRoomID)
+	if endDate == nil {
+		endDate = &today
+	}
+	if startDate == nil {
+		defaultStart := today.AddDate(0, 0, -30)
+		startDate = &defaultStart
+	}
+
+	// 在SQL中按用户聚合并计算密集排名
+	ranked := s.rangeQuery(chatRoomID, taskID, *startDate, *endDate).
+		Select("user_id, COUNT(*) AS check_in_count, COALESCE(SUM(duration_minutes), 0) AS total_duration, " +
+			"MAX(check_date) AS last_check_date, DENSE_RANK() OVER (ORDER BY " + score + " DESC) AS `rank`").
+		Group("user_id")
+
+	type rankRow struct {
+		UserID        int64
+		CheckInCount  int64
+		TotalDuration int64
+		LastCheckDate time.Time
+		Rank          int
+	}
+
+	leaderboard := &CheckInLeaderboard{Mode: mode, Page: page, PageSize: pageSize}
+	if err := s.db.Table("(?) AS r", ranked).Count(&leaderboard.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []rankRow
+	if err := s.db.Table("(?) AS r", ranked).
+		Order("`rank` ASC, user_id ASC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var me *rankRow
+	if viewerID != nil {
+		var mine []rankRow
+		if err := s.db.Table("(?) AS r", ranked).Where("user_id = ?", *viewerID).Scan(&mine).Error; err != nil {
+			return nil, err
 		}
-		userStats[checkIn.UserID]["count"] = userStats[checkIn.UserID]["count"].(int) + 1
-		if checkIn.CheckDate.After(userStats[checkIn.UserID]["last_check"].(time.Time)) {
-			userStats[checkIn.UserID]["last_check"] = checkIn.CheckDate
+		if len(mine) > 0 {
+			me = &mine[0]
 		}
 	}
 
-	// 转换为排行榜格式
-	type UserRank struct {
-		User      models.User `json:"user"`
-		Count     int         `json:"count"`
-		LastCheck time.Time   `json:"last_check"`
+	toEntry := func(row rankRow) LeaderboardEntry {
+		lastCheckDate := row.LastCheckDate
+		return LeaderboardEntry{
+			Rank:          row.Rank,
+			User:          models.User{ID: row.UserID},
+			CheckInCount:  row.CheckInCount,
+			TotalDuration: row.TotalDuration,
+			LastCheckDate: &lastCheckDate,
+		}
+	}
+
+	leaderboard.Entries = make([]LeaderboardEntry, 0, len(rows))
+	for _, row := range rows {
+		leaderboard.Entries = append(leaderboard.Entries, toEntry(row))
+	}
+	if me != nil {
+		entry := toEntry(*me)
+		leaderboard.Me = &entry
+	}
+
+	if err := s.fillLeaderboardUsers(leaderboard); err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
+}
+
+// getStreakLeaderboard 按指定任务的当前连续打卡周期数排名
+func (s *CheckInService) getStreakLeaderboard(chatRoomID int64, taskID *int64, page, pageSize int, viewerID *int64) (*CheckInLeaderboard, error) {
+	if taskID == nil {
+		return nil, errors.New("按连续打卡排名时需要指定打卡任务")
+	}
+
+	streaks, err := s.GetCheckInStreaks(chatRoomID, nil, taskID)
+	if err != nil {
+		return nil, err
 	}
 
-	var ranking []UserRank
-	for _, stats := range userStats {
-		ranking = append(ranking, UserRank{
-			User:      stats["user"].(models.User),
-			Count:     stats["count"].(int),
-			LastCheck: stats["last_check"].(time.Time),
+	// 连续记录已中断的成员不上榜
+	entries := make([]LeaderboardEntry, 0, len(streaks))
+	for _, streak := range streaks {
+		if streak.CurrentStreak == 0 {
+			continue
+		}
+		lastPeriodStart := streak.LastPeriodStart
+		entries = append(entries, LeaderboardEntry{
+			User:          models.User{ID: streak.UserID},
+			CurrentStreak: streak.CurrentStreak,
+			LastCheckDate: &lastPeriodStart,
 		})
 	}
 
-	// 按打卡次数排序
-	for i := 0; i < len(ranking)-1; i++ {
-		for j := i + 1; j < len(ranking); j++ {
-			if ranking[i].Count < ranking[j].Count {
-				ranking[i], ranking[j] = ranking[j], ranking[i]
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].CurrentStreak != entries[j].CurrentStreak {
+			return entries[i].CurrentStreak > entries[j].CurrentStreak
+		}
+		return entries[i].User.ID < entries[j].User.ID
+	})
+
+	leaderboard := &CheckInLeaderboard{Mode: "STREAK", Total: int64(len(entries)), Page: page, PageSize: pageSize}
+	for i := range entries {
+		entries[i].Rank = 1
+		if i > 0 {
+			entries[i].Rank = entries[i-1].Rank
+			if entries[i].CurrentStreak != entries[i-1].CurrentStreak {
+				entries[i].Rank++
 			}
 		}
+		if viewerID != nil && entries[i].User.ID == *viewerID {
+			entry := entries[i]
+			leaderboard.Me = &entry
+		}
+	}
+
+	start := (page - 1) * pageSize
+	if start > len(entries) {
+		start = len(entries)
+	}
+	end := start + pageSize
+	if end > len(entries) {
+		end = len(entries)
 	}
+	leaderboard.Entries = entries[start:end]
 
-	// 计算总体统计
-	totalCheckIns := len(checkIns)
-	uniqueUsers := len(userStats)
+	if err := s.fillLeaderboardUsers(leaderboard); err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
+}
 
-	// 计算连续打卡天数（以当前日期为基准）
-	continuousDays := s.calculateContinuousDays(chatRoomID)
+// fillLeaderboardUsers 一次查询补全排行榜中的用户信息
+func (s *CheckInService) fillLeaderboardUsers(leaderboard *CheckInLeaderboard) error {
+	userIDs := make([]int64, 0, len(leaderboard.Entries)+1)
+	for _, entry := range leaderboard.Entries {
+		userIDs = append(userIDs, entry.User.ID)
+	}
+	if leaderboard.Me != nil {
+		userIDs = append(userIDs, leaderboard.Me.User.ID)
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
 
-	return map[string]interface{}{
-		"total_check_ins": totalCheckIns,
-		"unique_users":    uniqueUsers,
-		"ranking":         ranking,
-		"continuous_days": continuousDays,
-	}, nil
+	var users []models.User
+	if err := s.db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+	userMap := make(map[int64]models.User, len(users))
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+
+	for i := range leaderboard.Entries {
+		if user, ok := userMap[leaderboard.Entries[i].User.ID]; ok {
+			leaderboard.Entries[i].User = user
+		}
+	}
+	if leaderboard.Me != nil {
+		if user, ok := userMap[leaderboard.Me.User.ID]; ok {
+			leaderboard.Me.User = user
+		}
+	}
+	return nil
 }
 
-// calculateContinuousDays 计算连续打卡天数
-func (s *CheckInService) calculateContinuousDays(chatRoomID int64) int {
-	continuousDays := 0
-	currentDate := time.Now()
+// CheckInStreak 用户在某个打卡任务上的连续打卡周期数（按任务周期计算，每日任务为天数、每周任务为周数、每月任务为月数）
+type CheckInStreak struct {
+	UserID          int64     `json:"userId"`
+	TaskID          int64     `json:"taskId"`
+	Cycle           string    `json:"cycle"`
+	CurrentStreak   int       `json:"currentStreak"`  // 截至当前周期的连续周期数，当前周期尚未打卡时从上一周期起算
+	CurrentMakeups  int       `json:"currentMakeups"` // 当前连续周期中通过补卡完成的周期数
+	LongestStreak   int       `json:"longestStreak"`
+	LastPeriodStart time.Time `json:"lastPeriodStart"`
+}
 
-	for {
-		dateStr := currentDate.Format("2006-01-02")
-		checkDate, _ := time.Parse("2006-01-02", dateStr)
+// GetCheckInStreaks 计算聊天室内每个用户在各打卡任务上的当前和最长连续打卡周期数，可按用户和任务过滤
+func (s *CheckInService) GetCheckInStreaks(chatRoomID int64, userID, taskID *int64) ([]CheckInStreak, error) {
+	type periodRow struct {
+		UserID      int64
+		TaskID      int64
+		Cycle       string
+		PeriodStart time.Time
+		IsMakeup    bool
+	}
 
-		var count int64
-		s.db.Model(&models.CheckIn{}).Where("chat_room_id = ? AND check_date = ?", chatRoomID, checkDate).Count(&count)
+	// 一次查询取出所有打卡周期，按用户、任务、周期排序后顺序计算
+	query := s.db.Table("checkin AS ci").
+		Select("ci.user_id, ci.task_id, t.cycle, ci.period_start, MAX(ci.is_makeup) AS is_makeup").
+		Joins("JOIN checkin_task AS t ON t.id = ci.task_id").
+		Where("ci.chat_room_id = ? AND ci.period_start IS NOT NULL", chatRoomID)
+	if userID != nil {
+		query = query.Where("ci.user_id = ?", *userID)
+	}
+	if taskID != nil {
+		query = query.Where("ci.task_id = ?", *taskID)
+	}
+
+	var rows []periodRow
+	if err := query.Group("ci.user_id, ci.task_id, t.cycle, ci.period_start").
+		Order("ci.user_id, ci.task_id, ci.period_start").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	streaks := make([]CheckInStreak, 0)
+	var current *CheckInStreak
+	for _, row := range rows {
+		if current == nil || current.UserID != row.UserID || current.TaskID != row.TaskID {
+			streaks = append(streaks, CheckInStreak{UserID: row.UserID, TaskID: row.TaskID, Cycle: row.Cycle})
+			current = &streaks[len(streaks)-1]
+		}
 
-		if count > 0 {
-			continuousDays++
-			currentDate = currentDate.AddDate(0, 0, -1)
+		// 与上一个打卡周期相邻时延续连续记录，否则重新计数
+		if current.CurrentStreak > 0 && sameDate(nextPeriodStart(row.Cycle, current.LastPeriodStart), row.PeriodStart) {
+			current.CurrentStreak++
 		} else {
-			break
+			current.CurrentStreak = 1
+			current.CurrentMakeups = 0
+		}
+		if row.IsMakeup {
+			current.CurrentMakeups++
+		}
+		if current.CurrentStreak > current.LongestStreak {
+			current.LongestStreak = current.CurrentStreak
 		}
+		current.LastPeriodStart = row.PeriodStart
 	}
 
-	return continuousDays
+	// 最后一次打卡既不在当前周期也不在上一周期时，连续记录已中断
+	today := s.roomToday(chatRoomID)
+	for i := range streaks {
+		periodStart := cyclePeriodStart(streaks[i].Cycle, today)
+		last := streaks[i].LastPeriodStart
+		if !sameDate(last, periodStart) && !sameDate(nextPeriodStart(streaks[i].Cycle, last), periodStart) {
+			streaks[i].CurrentStreak = 0
+			streaks[i].CurrentMakeups = 0
+		}
+	}
+
+	return streaks, nil
+}
+
+// nextPeriodStart 计算下一个打卡周期的第一天
+func nextPeriodStart(cycle string, periodStart time.Time) time.Time {
+	switch cycle {
+	case "WEEKLY":
+		return periodStart.AddDate(0, 0, 7)
+	case "MONTHLY":
+		return periodStart.AddDate(0, 1, 0)
+	default:
+		return periodStart.AddDate(0, 0, 1)
+	}
+}
+
+// sameDate 判断两个时间是否为同一日期（忽略时区）
+func sameDate(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
 }
 
-// GetUserCheckInHistory 获取用户打卡历史
-func (s *CheckInService) GetUserCheckInHistory(chatRoomID, userID int64, month string) ([]models.CheckIn, error) {
+// GetUserCheckInHistory 获取用户打卡历史，指定taskID时只返回该任务的记录；month为空时查询聊天室时区下的当前月份
+func (s *CheckInService) GetUserCheckInHistory(chatRoomID, userID int64, taskID *int64, month string) ([]models.CheckIn, error) {
 	var checkIns []models.CheckIn
 
+	if month == "" {
+		month = s.roomToday(chatRoomID).Format("2006-01")
+	}
+
 	// 解析月份
-	startDate, err := time.Parse("2006-01", month)
+	startDate, err := time.ParseInLocation("2006-01", month, time.Local)
 	if err != nil {
 		return nil, errors.New("无效的月份格式")
 	}
 
-	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
+	endDate := startDate.AddDate(0, 1, -1)
 
-	err = s.db.Where("chat_room_id = ? AND user_id = ? AND check_date >= ? AND check_date <= ?",
-		chatRoomID, userID, startDate, endDate).
-		Order("check_date ASC").
-		Find(&checkIns).Error
+	query := s.db.Where("chat_room_id = ? AND user_id = ? AND check_date >= ? AND check_date <= ?",
+		chatRoomID, userID, startDate, endDate)
+	if taskID != nil {
+		query = query.Where("task_id = ?", *taskID)
+	}
+
+	err = query.Preload("Attachments", orderAttachments).Order("check_date ASC").Find(&checkIns).Error
 
 	return checkIns, err
 }
 
-// GetTodayCheckInStatus 获取今天的打卡状态
-func (s *CheckInService) GetTodayCheckInStatus(chatRoomID, userID int64) (bool, *models.CheckIn, error) {
-	today := time.Now().Format("2006-01-02")
-	checkDate, _ := time.Parse("2006-01-02", today)
+// GetTodayCheckInStatus 获取今天的打卡状态，指定taskID时返回该任务当前周期的打卡状态
+func (s *CheckInService) GetTodayCheckInStatus(chatRoomID, userID int64, taskID *int64) (bool, *models.CheckIn, error) {
+	query := s.db.Where("chat_room_id = ? AND user_id = ?", chatRoomID, userID)
+	if taskID != nil {
+		var task models.CheckInTask
+		if err := s.db.First(&task, *taskID).Error; err != nil || task.ChatRoomID != chatRoomID {
+			return false, nil, errors.New("打卡任务不存在")
+		}
+		query = query.Where("task_id = ? AND period_start = ?", task.ID, cyclePeriodStart(task.Cycle, s.roomToday(chatRoomID)))
+	} else {
+		query = query.Where("check_date = ?", s.roomToday(chatRoomID))
+	}
 
 	var checkIn models.CheckIn
-	err := s.db.Where("chat_room_id = ? AND user_id = ? AND check_date = ?",
-		chatRoomID, userID, checkDate).First(&checkIn).Error
+	err := query.Preload("Attachments", orderAttachments).First(&checkIn).Error
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -279,3 +702,21 @@ func (s *CheckInService) GetTodayCheckInStatus(chatRoomID, userID int64) (bool,
 
 	return true, &checkIn, nil
 }
+
+// SetReminderEnabled 开启或关闭用户在聊天室的打卡提醒
+func (s *CheckInService) SetReminderEnabled(roomID, userID int64, enabled bool) error {
+	result := s.db.Model(&models.ChatRoomMember{}).
+		Where("chat_room_id = ? AND user_id = ?", roomID, userID).
+		Update("check_in_reminders_off", !enabled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		s.db.Model(&models.ChatRoomMember{}).Where("chat_room_id = ? AND user_id = ?", roomID, userID).Count(&count)
+		if count == 0 {
+			return errors.New("用户不是该聊天室成员")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"campus-canvas-chat/config"
+	"campus-canvas-chat/models"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// withCampusTimezone 临时修改校园默认时区，测试结束后恢复
+func withCampusTimezone(t *testing.T, timezone string) {
+	t.Helper()
+
+	cfg := config.GetConfig()
+	original := cfg.Campus.Timezone
+	cfg.Campus.Timezone = timezone
+	t.Cleanup(func() {
+		cfg.Campus.Timezone = original
+	})
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("加载时区%s失败: %v", name, err)
+	}
+	return loc
+}
+
+func TestCheckInDate(t *testing.T) {
+	shanghai := mustLoadLocation(t, "Asia/Shanghai")
+
+	tests := []struct {
+		name     string
+		t        time.Time
+		shanghai string
+		utc      string
+	}{
+		{"上海23:59", time.Date(2024, 3, 10, 23, 59, 0, 0, shanghai), "2024-03-10", "2024-03-10"},
+		{"上海00:00", time.Date(2024, 3, 11, 0, 0, 0, 0, shanghai), "2024-03-11", "2024-03-10"},
+		{"UTC23:59", time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC), "2024-03-11", "2024-03-10"},
+		{"UTC00:00", time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), "2024-03-11", "2024-03-11"},
+		{"上海跨月", time.Date(2024, 3, 1, 0, 0, 0, 0, shanghai), "2024-03-01", "2024-02-29"},
+		{"上海跨年", time.Date(2024, 1, 1, 0, 0, 0, 0, shanghai), "2024-01-01", "2023-12-31"},
+		{"UTC跨年前一分钟", time.Date(2023, 12, 31, 23, 59, 0, 0, time.UTC), "2024-01-01", "2023-12-31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range []struct {
+				loc  *time.Location
+				want string
+			}{{shanghai, tt.shanghai}, {time.UTC, tt.utc}} {
+				got := checkInDate(tt.t, c.loc)
+				if got.Format("2006-01-02") != c.want {
+					t.Errorf("checkInDate(%s, %s) = %s，期望 %s", tt.t.Format(time.RFC3339), c.loc, got.Format("2006-01-02"), c.want)
+				}
+				if got.Location() != time.Local || got.Hour() != 0 || got.Minute() != 0 {
+					t.Errorf("checkInDate(%s, %s) = %s，期望服务器本地时区的零点", tt.t.Format(time.RFC3339), c.loc, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCyclePeriodStart(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name  string
+		cycle string
+		date  time.Time
+		want  time.Time
+	}{
+		{"每日", "DAILY", date(2024, 3, 10), date(2024, 3, 10)},
+		{"每周周日属于上一周", "WEEKLY", date(2024, 3, 10), date(2024, 3, 4)},
+		{"每周周一开始新的一周", "WEEKLY", date(2024, 3, 11), date(2024, 3, 11)},
+		{"每周周六", "WEEKLY", date(2024, 3, 16), date(2024, 3, 11)},
+		{"每周跨月", "WEEKLY", date(2024, 5, 1), date(2024, 4, 29)},
+		{"每周跨年", "WEEKLY", date(2025, 1, 1), date(2024, 12, 30)},
+		{"每周年末周日", "WEEKLY", date(2023, 12, 31), date(2023, 12, 25)},
+		{"每月闰年二月末", "MONTHLY", date(2024, 2, 29), date(2024, 2, 1)},
+		{"每月月初", "MONTHLY", date(2024, 3, 1), date(2024, 3, 1)},
+		{"每月年末", "MONTHLY", date(2024, 12, 31), date(2024, 12, 1)},
+		{"每月跨年", "MONTHLY", date(2025, 1, 1), date(2025, 1, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cyclePeriodStart(tt.cycle, tt.date)
+			if !got.Equal(tt.want) {
+				t.Errorf("cyclePeriodStart(%s, %s) = %s，期望 %s", tt.cycle, tt.date.Format("2006-01-02"),
+					got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+// 周日深夜和周一零点在不同时区下落在不同的周
+func TestCyclePeriodStartWeekBoundaryAcrossTimezones(t *testing.T) {
+	shanghai := mustLoadLocation(t, "Asia/Shanghai")
+
+	tests := []struct {
+		name     string
+		t        time.Time
+		shanghai string
+		utc      string
+	}{
+		{"上海周日23:59", time.Date(2024, 3, 10, 23, 59, 0, 0, shanghai), "2024-03-04", "2024-03-04"},
+		{"上海周一00:00", time.Date(2024, 3, 11, 0, 0, 0, 0, shanghai), "2024-03-11", "2024-03-04"},
+		{"UTC周日23:59", time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC), "2024-03-11", "2024-03-04"},
+		{"上海跨年周一00:00", time.Date(2024, 12, 30, 0, 0, 0, 0, shanghai), "2024-12-30", "2024-12-23"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cyclePeriodStart("WEEKLY", checkInDate(tt.t, shanghai)).Format("2006-01-02"); got != tt.shanghai {
+				t.Errorf("上海时区的周期开始 = %s，期望 %s", got, tt.shanghai)
+			}
+			if got := cyclePeriodStart("WEEKLY", checkInDate(tt.t, time.UTC)).Format("2006-01-02"); got != tt.utc {
+				t.Errorf("UTC的周期开始 = %s，期望 %s", got, tt.utc)
+			}
+		})
+	}
+}
+
+func TestCampusLocation(t *testing.T) {
+	tests := []struct {
+		timezone string
+		want     string
+	}{
+		{"Asia/Shanghai", "Asia/Shanghai"},
+		{"UTC", "UTC"},
+		{"Invalid/Zone", time.Local.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			withCampusTimezone(t, tt.timezone)
+			if got := campusLocation().String(); got != tt.want {
+				t.Errorf("campusLocation() = %s，期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoomLocation(t *testing.T) {
+	db := openTestDB(t)
+	withCampusTimezone(t, "Asia/Shanghai")
+	creator := createTestUser(t, db, "creator")
+
+	createRoom := func(timezone string) int64 {
+		room := &models.ChatRoom{Name: "时区测试", Category: "测试", CreatorID: creator.ID, Timezone: timezone}
+		if err := db.Create(room).Error; err != nil {
+			t.Fatalf("创建聊天室失败: %v", err)
+		}
+		t.Cleanup(func() {
+			db.Unscoped().Delete(&models.ChatRoom{}, room.ID)
+		})
+		return room.ID
+	}
+
+	tests := []struct {
+		name   string
+		roomID int64
+		want   string
+	}{
+		{"聊天室设置了时区", createRoom("UTC"), "UTC"},
+		{"聊天室未设置时区", createRoom(""), "Asia/Shanghai"},
+		{"聊天室时区无效", createRoom("Invalid/Zone"), "Asia/Shanghai"},
+		{"聊天室不存在", -1, "Asia/Shanghai"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roomLocation(db, tt.roomID).String(); got != tt.want {
+				t.Errorf("roomLocation(%d) = %s，期望 %s", tt.roomID, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/filter"
+	"campus-canvas-chat/models"
+	campusredis "campus-canvas-chat/redis"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// filterReloadChannel 敏感词变更通知频道，各实例收到后从MySQL重新加载词表
+const filterReloadChannel = "content_filter:reload"
+
+// 所有实例共享的敏感词匹配器
+var (
+	filterMutex   sync.RWMutex
+	filterMatcher *filter.Matcher
+	filterWords   []models.SensitiveWord
+)
+
+// LoadSensitiveWords 从MySQL加载启用的敏感词并重建匹配器
+func LoadSensitiveWords() error {
+	var words []models.SensitiveWord
+	if err := database.GetDB().Where("is_active = ?", true).Find(&words).Error; err != nil {
+		return err
+	}
+
+	patterns := make([]string, len(words))
+	for i, word := range words {
+		patterns[i] = word.Word
+	}
+	matcher := filter.NewMatcher(patterns)
+
+	filterMutex.Lock()
+	filterMatcher = matcher
+	filterWords = words
+	filterMutex.Unlock()
+
+	log.Printf("敏感词加载完成，共 %d 个", len(words))
+	return nil
+}
+
+// StartFilterReloadListener 监听敏感词变更通知并热加载（阻塞运行）
+func StartFilterReloadListener() {
+	pubsub := campusredis.Subscribe(filterReloadChannel)
+	defer pubsub.Close()
+
+	for range pubsub.Channel() {
+		if err := LoadSensitiveWords(); err != nil {
+			log.Printf("重新加载敏感词失败: %v", err)
+		}
+	}
+}
+
+// publishFilterReload 通知所有实例重新加载敏感词
+func publishFilterReload() {
+	if err := campusredis.Publish(filterReloadChannel, time.Now().String()); err != nil {
+		// Redis不可用时至少保证当前实例生效
+		log.Printf("发布敏感词变更通知失败: %v", err)
+		LoadSensitiveWords()
+	}
+}
+
+// actionSeverity 敏感词处理方式的严重程度
+var actionSeverity = map[string]int{
+	"FLAG":   1,
+	"MASK":   2,
+	"REJECT": 3,
+}
+
+type ContentFilterService struct {
+	db *gorm.DB
+}
+
+func NewContentFilterService() *ContentFilterService {
+	return &ContentFilterService{
+		db: database.GetDB(),
+	}
+}
+
+// Apply 对内容进行敏感词过滤，返回处理后的内容
+// 命中REJECT词时拒绝，命中MASK词时用*替换，命中FLAG词时原样通过并记录待审
+func (s *ContentFilterService) Apply(source string, userID int64, chatRoomID *int64, content string) (string, error) {
+	filterMutex.RLock()
+	matcher := filterMatcher
+	words := filterWords
+	filterMutex.RUnlock()
+
+	matches := matcher.FindAll(content)
+	if len(matches) == 0 {
+		return content, nil
+	}
+
+	// 找出最严重的处理方式，并收集需要打码的位置
+	severest := ""
+	hitWords := make(map[int64]models.SensitiveWord)
+	maskMatches := make([]filter.Match, 0, len(matches))
+	for _, match := range matches {
+		word := words[match.Index]
+		hitWords[word.ID] = word
+
+		if actionSeverity[word.Action] > actionSeverity[severest] {
+			severest = word.Action
+		}
+
+		if word.Action == "MASK" {
+			maskMatches = append(maskMatches, match)
+		}
+	}
+
+	// 记录命中情况供管理员审核
+	hits := make([]models.ContentFilterHit, 0, len(hitWords))
+	for _, word := range hitWords {
+		hits = append(hits, models.ContentFilterHit{
+			WordID:       word.ID,
+			Word:         word.Word,
+			Action:       word.Action,
+			Source:       source,
+			UserID:       userID,
+			ChatRoomID:   chatRoomID,
+			Content:      content,
+			ReviewStatus: "PENDING",
+			CreatedAt:    time.Now(),
+		})
+	}
+	if err := s.db.Create(&hits).Error; err != nil {
+		log.Printf("记录敏感词命中失败: %v", err)
+	}
+
+	if severest == "REJECT" {
+		return "", errors.New("内容包含违禁词")
+	}
+
+	return filter.Mask(content, maskMatches), nil
+}
+
+// GetSensitiveWords 获取敏感词列表
+func (s *ContentFilterService) GetSensitiveWords(keyword string, page, pageSize int) ([]models.SensitiveWord, int64, error) {
+	var words []models.SensitiveWord
+	var total int64
+
+	query := s.db.Model(&models.SensitiveWord{})
+	if keyword != "" {
+		query = query.Where("word LIKE ?", "%"+keyword+"%")
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&words).Error
+
+	return words, total, err
+}
+
+// CreateSensitiveWord 添加敏感词
+func (s *ContentFilterService) CreateSensitiveWord(adminUserID int64, word, action string) (*models.SensitiveWord, error) {
+	var existing models.SensitiveWord
+	if err := s.db.Where("word = ?", word).First(&existing).Error; err == nil {
+		return nil, errors.New("敏感词已存在")
+	}
+
+	sensitiveWord := &models.SensitiveWord{
+		Word:      word,
+		Action:    action,
+		IsActive:  true,
+		CreatorID: adminUserID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(sensitiveWord).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, adminUserID, "CREATE_SENSITIVE_WORD", "SENSITIVE_WORD", sensitiveWord.ID, nil, nil,
+			map[string]interface{}{"word": word, "action": action},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishFilterReload()
+	return sensitiveWord, nil
+}
+
+// UpdateSensitiveWord 修改敏感词的处理方式或启用状态
+func (s *ContentFilterService) UpdateSensitiveWord(adminUserID, wordID int64, updates map[string]interface{}) error {
+	var word models.SensitiveWord
+	if err := s.db.First(&word, wordID).Error; err != nil {
+		return errors.New("敏感词不存在")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		before := map[string]interface{}{"action": word.Action, "isActive": word.IsActive}
+		if err := tx.Model(&word).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, adminUserID, "UPDATE_SENSITIVE_WORD", "SENSITIVE_WORD", wordID, nil, before,
+			map[string]interface{}{"action": word.Action, "isActive": word.IsActive},
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	publishFilterReload()
+	return nil
+}
+
+// DeleteSensitiveWord 删除敏感词
+func (s *ContentFilterService) DeleteSensitiveWord(adminUserID, wordID int64) error {
+	var word models.SensitiveWord
+	if err := s.db.First(&word, wordID).Error; err != nil {
+		return errors.New("敏感词不存在")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&word).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, adminUserID, "DELETE_SENSITIVE_WORD", "SENSITIVE_WORD", wordID, nil,
+			map[string]interface{}{"word": word.Word, "action": word.Action},
+			nil,
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	publishFilterReload()
+	return nil
+}
+
+// GetFilterHits 获取敏感词命中记录
+func (s *ContentFilterService) GetFilterHits(reviewStatus, source string, page, pageSize int) ([]models.ContentFilterHit, int64, error) {
+	var hits []models.ContentFilterHit
+	var total int64
+
+	query := s.db.Model(&models.ContentFilterHit{})
+	if reviewStatus != "" {
+		query = query.Where("review_status = ?", reviewStatus)
+	}
+	if source != "" {
+		query = query.Where("source = ?", source)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&hits).Error
+
+	return hits, total, err
+}
+
+// ReviewFilterHit 将命中记录标记为已审核
+func (s *ContentFilterService) ReviewFilterHit(adminUserID, hitID int64) error {
+	result := s.db.Model(&models.ContentFilterHit{}).
+		Where("id = ? AND review_status = ?", hitID, "PENDING").
+		Updates(map[string]interface{}{
+			"review_status": "REVIEWED",
+			"reviewer_id":   adminUserID,
+			"reviewed_at":   time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("命中记录不存在或已审核")
+	}
+
+	return nil
+}
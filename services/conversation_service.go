@@ -0,0 +1,429 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 多人会话的人数范围（含创建者）
+const (
+	minGroupConversationSize = 3
+	maxGroupConversationSize = 10
+)
+
+type ConversationService struct {
+	db            *gorm.DB
+	contentFilter *ContentFilterService
+}
+
+func NewConversationService() *ConversationService {
+	return &ConversationService{
+		db:            database.GetDB(),
+		contentFilter: NewContentFilterService(),
+	}
+}
+
+// CreateGroupConversation 创建多人会话，memberIDs不含创建者
+func (s *ConversationService) CreateGroupConversation(creatorID int64, name string, memberIDs []int64) (*models.Conversation, error) {
+	var creator models.User
+	if err := s.db.First(&creator, creatorID).Error; err != nil {
+		return nil, errors.New("创建者不存在")
+	}
+	if creator.Status != "ACTIVE" {
+		return nil, errors.New("账号已被禁用")
+	}
+
+	userIDs := uniqueUserIDs(creatorID, memberIDs)
+	if len(userIDs) < minGroupConversationSize || len(userIDs) > maxGroupConversationSize {
+		return nil, errors.New("多人会话人数需在3到10人之间")
+	}
+
+	if err := s.checkUsersActive(userIDs); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkInvitePermission(creatorID, userIDs[1:]); err != nil {
+		return nil, err
+	}
+
+	conversation := &models.Conversation{
+		Type:      "GROUP",
+		Name:      name,
+		CreatorID: &creatorID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(conversation).Error; err != nil {
+			return err
+		}
+
+		participants := make([]models.ConversationParticipant, 0, len(userIDs))
+		for _, userID := range userIDs {
+			role := "MEMBER"
+			if userID == creatorID {
+				role = "OWNER"
+			}
+			participants = append(participants, models.ConversationParticipant{
+				ConversationID: conversation.ID,
+				UserID:         userID,
+				Role:           role,
+				JoinedAt:       time.Now(),
+			})
+		}
+		if err := tx.Create(&participants).Error; err != nil {
+			return err
+		}
+
+		conversation.Participants = participants
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conversation, nil
+}
+
+// GetConversation 获取会话详情（仅参与者可查看）
+func (s *ConversationService) GetConversation(conversationID, userID int64) (*models.Conversation, error) {
+	if _, err := s.getParticipant(conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	var conversation models.Conversation
+	err := s.db.Preload("Participants").
+		Preload("LastMessage").
+		First(&conversation, conversationID).Error
+	if err != nil {
+		return nil, errors.New("会话不存在")
+	}
+
+	return &conversation, nil
+}
+
+// RenameConversation 修改多人会话名称（参与者均可修改）
+func (s *ConversationService) RenameConversation(conversationID, operatorID int64, name string) error {
+	conversation, err := s.getGroupConversation(conversationID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.getParticipant(conversationID, operatorID); err != nil {
+		return err
+	}
+
+	return s.db.Model(conversation).Updates(map[string]interface{}{
+		"name":       name,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// AddParticipants 邀请用户加入多人会话（参与者均可邀请）
+func (s *ConversationService) AddParticipants(conversationID, operatorID int64, userIDs []int64) ([]models.ConversationParticipant, error) {
+	if _, err := s.getGroupConversation(conversationID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.getParticipant(conversationID, operatorID); err != nil {
+		return nil, err
+	}
+
+	var participants []models.ConversationParticipant
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定会话行，保证并发邀请时人数上限检查和插入的一致性
+		var conversation models.Conversation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&conversation, conversationID).Error; err != nil {
+			return errors.New("会话不存在")
+		}
+
+		var existingIDs []int64
+		if err := tx.Model(&models.ConversationParticipant{}).
+			Where("conversation_id = ?", conversationID).
+			Pluck("user_id", &existingIDs).Error; err != nil {
+			return err
+		}
+
+		existing := make(map[int64]bool, len(existingIDs))
+		for _, id := range existingIDs {
+			existing[id] = true
+		}
+
+		var newIDs []int64
+		for _, id := range uniqueUserIDs(0, userIDs) {
+			if !existing[id] {
+				newIDs = append(newIDs, id)
+			}
+		}
+
+		if len(newIDs) == 0 {
+			return errors.New("用户已在会话中")
+		}
+		if len(existingIDs)+len(newIDs) > maxGroupConversationSize {
+			return errors.New("多人会话最多10人")
+		}
+
+		if err := s.checkUsersActive(newIDs); err != nil {
+			return err
+		}
+
+		if err := s.checkInvitePermission(operatorID, newIDs); err != nil {
+			return err
+		}
+
+		participants = make([]models.ConversationParticipant, 0, len(newIDs))
+		for _, id := range newIDs {
+			participants = append(participants, models.ConversationParticipant{
+				ConversationID: conversationID,
+				UserID:         id,
+				Role:           "MEMBER",
+				JoinedAt:       time.Now(),
+			})
+		}
+
+		return tx.Create(&participants).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return participants, nil
+}
+
+// RemoveParticipant 移出多人会话参与者，操作者为本人时表示退出；创建者退出时转交给最早加入的成员
+func (s *ConversationService) RemoveParticipant(conversationID, operatorID, targetUserID int64) error {
+	if _, err := s.getGroupConversation(conversationID); err != nil {
+		return err
+	}
+
+	operator, err := s.getParticipant(conversationID, operatorID)
+	if err != nil {
+		return err
+	}
+
+	target := operator
+	if targetUserID != operatorID {
+		if operator.Role != "OWNER" {
+			return errors.New("只有会话创建者可以移出成员")
+		}
+		if target, err = s.getParticipant(conversationID, targetUserID); err != nil {
+			return errors.New("目标用户不在会话中")
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(target).Error; err != nil {
+			return err
+		}
+
+		// 清除离开者的未读计数
+		if err := tx.Where("conversation_id = ? AND user_id = ?", conversationID, target.UserID).
+			Delete(&models.ConversationUnreadCount{}).Error; err != nil {
+			return err
+		}
+
+		if target.Role != "OWNER" {
+			return nil
+		}
+
+		var successor models.ConversationParticipant
+		if err := tx.Where("conversation_id = ?", conversationID).Order("joined_at ASC, id ASC").First(&successor).Error; err != nil {
+			return nil
+		}
+		return tx.Model(&successor).Update("role", "OWNER").Error
+	})
+}
+
+// CheckSendPermission 检查用户是否可以在多人会话中发言（会话存在、是参与者、账号未被禁用）
+func (s *ConversationService) CheckSendPermission(conversationID, senderID int64) error {
+	if _, err := s.getGroupConversation(conversationID); err != nil {
+		return err
+	}
+
+	if _, err := s.getParticipant(conversationID, senderID); err != nil {
+		return err
+	}
+
+	var sender models.User
+	if err := s.db.First(&sender, senderID).Error; err != nil {
+		return errors.New("发送者不存在")
+	}
+	if sender.Status != "ACTIVE" {
+		return errors.New("账号已被禁用")
+	}
+
+	return nil
+}
+
+// SendConversationMessage 在多人会话中发送消息，返回消息和需要推送的其他参与者
+func (s *ConversationService) SendConversationMessage(conversationID, senderID int64, content string) (*models.PrivateMessage, []int64, error) {
+	if err := s.CheckSendPermission(conversationID, senderID); err != nil {
+		return nil, nil, err
+	}
+
+	// 敏感词过滤
+	content, err := s.contentFilter.Apply("PRIVATE_MESSAGE", senderID, nil, content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message := &models.PrivateMessage{
+		SenderID:       senderID,
+		ConversationID: &conversationID,
+		Content:        content,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	// 保存消息、更新会话和未读计数在同一事务中完成
+	var recipientIDs []int64
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Conversation{}).Where("id = ?", conversationID).Updates(map[string]interface{}{
+			"last_message_id":   message.ID,
+			"last_message_time": message.CreatedAt,
+			"updated_at":        time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		// 增加其他参与者的未读计数
+		if err := tx.Model(&models.ConversationParticipant{}).
+			Where("conversation_id = ? AND user_id <> ?", conversationID, senderID).
+			Pluck("user_id", &recipientIDs).Error; err != nil {
+			return err
+		}
+		for _, userID := range recipientIDs {
+			if err := incrementConversationUnreadCount(tx, conversationID, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return message, recipientIDs, nil
+}
+
+// GetConversationMessages 获取会话消息列表（仅参与者可查看）
+func (s *ConversationService) GetConversationMessages(conversationID, userID int64, page, pageSize int) ([]models.PrivateMessage, int64, error) {
+	participant, err := s.getParticipant(conversationID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var messages []models.PrivateMessage
+	var total int64
+
+	query := s.db.Model(&models.PrivateMessage{}).Where("conversation_id = ?", conversationID)
+
+	// 不显示删除会话之前的消息
+	if participant.ClearedAt != nil {
+		query = query.Where("created_at > ?", *participant.ClearedAt)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询，按时间倒序
+	offset := (page - 1) * pageSize
+	err = query.Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&messages).Error
+
+	return messages, total, err
+}
+
+// getGroupConversation 获取多人会话
+func (s *ConversationService) getGroupConversation(conversationID int64) (*models.Conversation, error) {
+	var conversation models.Conversation
+	if err := s.db.First(&conversation, conversationID).Error; err != nil {
+		return nil, errors.New("会话不存在")
+	}
+
+	if conversation.Type != "GROUP" {
+		return nil, errors.New("该会话不是多人会话")
+	}
+
+	return &conversation, nil
+}
+
+// getParticipant 获取会话参与者记录
+func (s *ConversationService) getParticipant(conversationID, userID int64) (*models.ConversationParticipant, error) {
+	var participant models.ConversationParticipant
+	if err := s.db.Where("conversation_id = ? AND user_id = ?", conversationID, userID).First(&participant).Error; err != nil {
+		return nil, errors.New("用户不在该会话中")
+	}
+	return &participant, nil
+}
+
+// checkUsersActive 检查用户是否都存在且处于正常状态
+func (s *ConversationService) checkUsersActive(userIDs []int64) error {
+	var count int64
+	if err := s.db.Model(&models.User{}).
+		Where("id IN ? AND status = ?", userIDs, "ACTIVE").
+		Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count != int64(len(userIDs)) {
+		return errors.New("部分用户不存在或已被禁用")
+	}
+
+	return nil
+}
+
+// checkInvitePermission 检查操作者能否将用户拉入多人会话，规则与私信一致：双方均未拉黑对方，且满足被邀请者的私信权限
+func (s *ConversationService) checkInvitePermission(operatorID int64, userIDs []int64) error {
+	for _, userID := range userIDs {
+		if isUserBlocked(s.db, userID, operatorID) {
+			return errors.New("部分用户已拒绝接收你的消息")
+		}
+
+		if isUserBlocked(s.db, operatorID, userID) {
+			return errors.New("你已拉黑部分用户，请先解除拉黑")
+		}
+
+		setting, err := getChatSetting(s.db, userID)
+		if err != nil {
+			return err
+		}
+
+		if setting.DMPolicy == "SHARED_ROOM" && !shareChatRoom(s.db, operatorID, userID) {
+			return errors.New("部分用户仅接收同一聊天室成员的私信")
+		}
+	}
+
+	return nil
+}
+
+// uniqueUserIDs 去重用户ID，firstID不为0时放在首位
+func uniqueUserIDs(firstID int64, userIDs []int64) []int64 {
+	seen := make(map[int64]bool, len(userIDs)+1)
+	result := make([]int64, 0, len(userIDs)+1)
+	if firstID != 0 {
+		seen[firstID] = true
+		result = append(result, firstID)
+	}
+	for _, id := range userIDs {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
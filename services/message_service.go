@@ -14,31 +14,52 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MessageService struct {
-	db          *gorm.DB
-	redisClient *redis.Client
+	db            *gorm.DB
+	redisClient   *redis.Client
+	contentFilter *ContentFilterService
 }
 
 func NewMessageService() *MessageService {
 	return &MessageService{
-		db:          database.GetDB(),
-		redisClient: campusredis.GetClient(),
+		db:            database.GetDB(),
+		redisClient:   campusredis.GetClient(),
+		contentFilter: NewContentFilterService(),
 	}
 }
 
 // SendGroupMessage 发送群聊消息（先存入Redis，缓存存入失败后再写入MySQL）
-func (s *MessageService) SendGroupMessage(chatRoomID, userID int64, content string) (*models.Message, error) {
+// clientMsgID不为空时在去重窗口内重复发送会返回原消息，duplicate为true
+func (s *MessageService) SendGroupMessage(chatRoomID, userID int64, content, clientMsgID string) (message *models.Message, duplicate bool, err error) {
+	// 客户端重试时返回原消息
+	if clientMsgID != "" {
+		claimed, cached := s.ClaimClientMsgID("group", userID, clientMsgID)
+		if !claimed {
+			return s.duplicateGroupMessage(userID, clientMsgID, cached)
+		}
+		defer func() {
+			if err != nil {
+				s.ReleaseClientMsgID("group", userID, clientMsgID)
+				return
+			}
+			data, _ := json.Marshal(message)
+			s.SaveClientMsgResult("group", userID, clientMsgID, data)
+		}()
+	}
+
 	// 检查用户是否是聊天室成员且未被禁言
-	// var member models.ChatRoomMember
-	// if err := s.db.Where("chat_room_id = ? AND user_id = ?", chatRoomID, userID).First(&member).Error; err != nil {
-	// 	return nil, errors.New("用户不是该聊天室成员")
-	// }
+	if err := s.CheckGroupSendPermission(chatRoomID, userID); err != nil {
+		return nil, false, err
+	}
 
-	// if member.IsMuted {
-	//     return nil, errors.New("用户已被禁言")
-	// }
+	// 敏感词过滤
+	content, err = s.contentFilter.Apply("GROUP_MESSAGE", userID, &chatRoomID, content)
+	if err != nil {
+		return nil, false, err
+	}
 
 	// // 获取用户名
 	// var username string
@@ -47,24 +68,27 @@ func (s *MessageService) SendGroupMessage(chatRoomID, userID int64, content stri
 	// }
 
 	// 创建消息对象
-	message := &models.Message{
+	message = &models.Message{
 		ChatRoomID: chatRoomID,
 		UserID:     userID,
 		Content:    content,
 		CreatedAt:  time.Now(),
 	}
+	if clientMsgID != "" {
+		message.ClientMsgID = &clientMsgID
+	}
 
 	// 将消息转换为JSON
 	messageJSON, err := json.Marshal(message)
 	if err != nil {
-		return nil, errors.New("消息序列化失败")
+		return nil, false, errors.New("消息序列化失败")
 	}
 
 	// 生成随机Redis键名
 	randomBytes := make([]byte, 16)
 	_, err = rand.Read(randomBytes)
 	if err != nil {
-		return nil, errors.New("生成随机键失败: " + err.Error())
+		return nil, false, errors.New("生成随机键失败: " + err.Error())
 	}
 
 	// 使用Base64编码随机字节，并添加前缀和聊天室ID
@@ -83,17 +107,79 @@ func (s *MessageService) SendGroupMessage(chatRoomID, userID int64, content stri
 		// Redis存储失败，记录日志
 		fmt.Printf("Redis存储消息失败: %v，将消息存入MySQL\n", err)
 
-		// 存入MySQL数据库
+		// 存入MySQL数据库，Redis不可用时由唯一索引保证不重复插入
 		if err := s.db.Create(message).Error; err != nil {
-			return nil, errors.New("发送消息失败: " + err.Error())
+			if clientMsgID != "" {
+				var existing models.Message
+				if s.db.Where("user_id = ? AND client_msg_id = ?", userID, clientMsgID).First(&existing).Error == nil {
+					return &existing, true, nil
+				}
+			}
+			return nil, false, errors.New("发送消息失败: " + err.Error())
 		}
 	}
 
-	return message, nil
+	return message, false, nil
 }
 
-// GetGroupMessages 获取群聊消息列表
-func (s *MessageService) GetGroupMessages(chatRoomID int64, page, pageSize int) ([]models.Message, int64, error) {
+// duplicateGroupMessage 返回重复发送的群聊消息，原消息仍在发送中时返回错误
+func (s *MessageService) duplicateGroupMessage(userID int64, clientMsgID, cached string) (*models.Message, bool, error) {
+	var message models.Message
+	if cached != "" && json.Unmarshal([]byte(cached), &message) == nil {
+		return &message, true, nil
+	}
+	if s.db.Where("user_id = ? AND client_msg_id = ?", userID, clientMsgID).First(&message).Error == nil {
+		return &message, true, nil
+	}
+	return nil, false, errors.New("消息正在发送中，请勿重复提交")
+}
+
+// FindGroupMessageByClientMsgID 查找去重窗口内已发送成功的群聊消息，供重试请求在限流前直接返回
+func (s *MessageService) FindGroupMessageByClientMsgID(userID int64, clientMsgID string) (*models.Message, bool) {
+	message, duplicate, err := s.duplicateGroupMessage(userID, clientMsgID, s.CachedClientMsgResult("group", userID, clientMsgID))
+	return message, err == nil && duplicate
+}
+
+// CheckGroupSendPermission 检查用户是否可以在聊天室发言（账号状态、成员身份、个人禁言、全员禁言）
+func (s *MessageService) CheckGroupSendPermission(chatRoomID, userID int64) error {
+	if err := checkUserActive(s.db, userID); err != nil {
+		return err
+	}
+
+	var member models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ? AND user_id = ?", chatRoomID, userID).First(&member).Error; err != nil {
+		return errors.New("用户不是该聊天室成员")
+	}
+
+	if isMuteActive(&member) {
+		return errors.New("用户已被禁言")
+	}
+
+	// 定时禁言已到期，顺带清除禁言状态
+	if member.IsMuted {
+		s.db.Model(&member).Updates(map[string]interface{}{
+			"is_muted":    false,
+			"muted_until": nil,
+			"mute_reason": "",
+		})
+	}
+
+	// 全员禁言时只有房主和管理员可以发言
+	if member.Role == "MEMBER" {
+		var room models.ChatRoom
+		if err := s.db.Select("id, all_muted").First(&room, chatRoomID).Error; err != nil {
+			return errors.New("聊天室不存在")
+		}
+		if room.AllMuted {
+			return errors.New("聊天室已开启全员禁言")
+		}
+	}
+
+	return nil
+}
+
+// GetGroupMessages 获取群聊消息列表，指定viewerID时按其设置隐藏已拉黑用户的消息
+func (s *MessageService) GetGroupMessages(chatRoomID int64, viewerID *int64, page, pageSize int) ([]models.Message, int64, error) {
 	var messages []models.Message
 	var total int64
 
@@ -103,15 +189,28 @@ func (s *MessageService) GetGroupMessages(chatRoomID int64, page, pageSize int)
 		return nil, 0, errors.New("聊天室不存在")
 	}
 
+	query := s.db.Model(&models.Message{}).Where("chat_room_id = ?", chatRoomID)
+
+	// 隐藏已拉黑用户的消息
+	if viewerID != nil {
+		setting, err := getChatSetting(s.db, *viewerID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if setting.HideBlockedInGroups {
+			query = query.Where("user_id NOT IN (?)",
+				s.db.Model(&models.UserBlock{}).Select("blocked_id").Where("blocker_id = ?", *viewerID))
+		}
+	}
+
 	// 获取总数
-	if err := s.db.Model(&models.Message{}).Where("chat_room_id = ?", chatRoomID).Count(&total).Error; err != nil {
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询消息
 	offset := (page - 1) * pageSize
-	err := s.db.Where("chat_room_id = ?", chatRoomID).
-		Order("created_at DESC").
+	err := query.Order("created_at DESC").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&messages).Error
@@ -120,80 +219,222 @@ func (s *MessageService) GetGroupMessages(chatRoomID int64, page, pageSize int)
 }
 
 // SendPrivateMessage 发送私聊消息（持久化存储）
-func (s *MessageService) SendPrivateMessage(senderID, receiverID int64, content string) (*models.PrivateMessage, error) {
-	// 检查发送者和接收者是否存在
-	var sender, receiver models.User
-	if err := s.db.First(&sender, senderID).Error; err != nil {
-		return nil, errors.New("发送者不存在")
+// clientMsgID不为空时在去重窗口内重复发送会返回原消息，duplicate为true
+func (s *MessageService) SendPrivateMessage(senderID, receiverID int64, content, clientMsgID string) (message *models.PrivateMessage, duplicate bool, err error) {
+	// 客户端重试时返回原消息
+	if clientMsgID != "" {
+		claimed, cached := s.ClaimClientMsgID("private", senderID, clientMsgID)
+		if !claimed {
+			return s.duplicatePrivateMessage(senderID, clientMsgID, cached)
+		}
+		defer func() {
+			if err != nil {
+				s.ReleaseClientMsgID("private", senderID, clientMsgID)
+				return
+			}
+			data, _ := json.Marshal(message)
+			s.SaveClientMsgResult("private", senderID, clientMsgID, data)
+		}()
 	}
-	if err := s.db.First(&receiver, receiverID).Error; err != nil {
-		return nil, errors.New("接收者不存在")
+
+	// 检查发送者、接收者、拉黑关系和接收者的私信设置
+	if err := s.CheckPrivateSendPermission(senderID, receiverID); err != nil {
+		return nil, false, err
+	}
+
+	// 敏感词过滤
+	content, err = s.contentFilter.Apply("PRIVATE_MESSAGE", senderID, nil, content)
+	if err != nil {
+		return nil, false, err
 	}
 
 	// 创建私聊消息
-	message := &models.PrivateMessage{
+	message = &models.PrivateMessage{
 		SenderID:   senderID,
 		ReceiverID: receiverID,
 		Content:    content,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}
+	if clientMsgID != "" {
+		message.ClientMsgID = &clientMsgID
+	}
+
+	// 保存消息、更新会话和未读计数在同一事务中完成
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+
+		// 更新或创建会话记录
+		conversationID, err := upsertDirectConversation(tx, senderID, receiverID, message.ID, message.CreatedAt)
+		if err != nil {
+			return err
+		}
 
-	// 保存到数据库
-	if err := s.db.Create(message).Error; err != nil {
-		return nil, err
+		// 关联会话并增加接收者在该会话的未读消息计数
+		if err := tx.Model(message).Update("conversation_id", conversationID).Error; err != nil {
+			return err
+		}
+		if err := incrementConversationUnreadCount(tx, conversationID, receiverID); err != nil {
+			return err
+		}
+
+		// 重新查询消息以获取最新数据
+		return tx.First(message, message.ID).Error
+	})
+	if err != nil {
+		// Redis不可用时由唯一索引保证不重复插入
+		if clientMsgID != "" {
+			var existing models.PrivateMessage
+			if s.db.Where("sender_id = ? AND client_msg_id = ?", senderID, clientMsgID).First(&existing).Error == nil {
+				return &existing, true, nil
+			}
+		}
+		return nil, false, err
 	}
 
-	// 重新查询消息以获取最新数据
-	if err := s.db.First(message, message.ID).Error; err != nil {
-		return nil, err
+	return message, false, nil
+}
+
+// duplicatePrivateMessage 返回重复发送的私聊消息，原消息仍在发送中时返回错误
+func (s *MessageService) duplicatePrivateMessage(senderID int64, clientMsgID, cached string) (*models.PrivateMessage, bool, error) {
+	var message models.PrivateMessage
+	if cached != "" && json.Unmarshal([]byte(cached), &message) == nil {
+		return &message, true, nil
 	}
+	if s.db.Where("sender_id = ? AND client_msg_id = ?", senderID, clientMsgID).First(&message).Error == nil {
+		return &message, true, nil
+	}
+	return nil, false, errors.New("消息正在发送中，请勿重复提交")
+}
 
-	// 更新或创建会话记录
-	conversationID := s.updateConversation(senderID, receiverID, message.ID, message.CreatedAt)
+// FindPrivateMessageByClientMsgID 查找去重窗口内已发送成功的私聊消息，供重试请求在限流前直接返回
+func (s *MessageService) FindPrivateMessageByClientMsgID(senderID int64, clientMsgID string) (*models.PrivateMessage, bool) {
+	message, duplicate, err := s.duplicatePrivateMessage(senderID, clientMsgID, s.CachedClientMsgResult("private", senderID, clientMsgID))
+	return message, err == nil && duplicate
+}
+
+// clientMsgTTL 客户端消息ID的去重时间窗口
+const clientMsgTTL = 24 * time.Hour
+
+// clientMsgKey 客户端消息ID在Redis中的键名
+func clientMsgKey(scope string, senderID int64, clientMsgID string) string {
+	return fmt.Sprintf("client_msg:%s:%d:%s", scope, senderID, clientMsgID)
+}
+
+// ClaimClientMsgID 在去重窗口内占用发送者的客户端消息ID
+// 已被占用时claimed为false，cached为原消息的发送结果（原消息仍在发送中时为空）；Redis不可用时视为占用成功
+func (s *MessageService) ClaimClientMsgID(scope string, senderID int64, clientMsgID string) (claimed bool, cached string) {
+	ctx := context.Background()
+	key := clientMsgKey(scope, senderID, clientMsgID)
 
-	// 增加接收者在该会话的未读消息计数
-	if conversationID > 0 {
-		s.incrementConversationUnreadCount(conversationID, receiverID)
+	ok, err := s.redisClient.SetNX(ctx, key, "", clientMsgTTL).Result()
+	if err != nil || ok {
+		return true, ""
 	}
 
-	return message, nil
+	cached, _ = s.redisClient.Get(ctx, key).Result()
+	return false, cached
+}
+
+// CachedClientMsgResult 读取客户端消息ID缓存的发送结果，未发送或仍在发送中时返回空
+func (s *MessageService) CachedClientMsgResult(scope string, senderID int64, clientMsgID string) string {
+	cached, _ := s.redisClient.Get(context.Background(), clientMsgKey(scope, senderID, clientMsgID)).Result()
+	return cached
+}
+
+// SaveClientMsgResult 缓存客户端消息ID对应的发送结果，重试时直接返回
+func (s *MessageService) SaveClientMsgResult(scope string, senderID int64, clientMsgID string, data []byte) {
+	s.redisClient.Set(context.Background(), clientMsgKey(scope, senderID, clientMsgID), string(data), clientMsgTTL)
 }
 
-// updateConversation 更新会话记录，返回会话ID
-func (s *MessageService) updateConversation(user1ID, user2ID, messageID int64, messageTime time.Time) int64 {
+// ReleaseClientMsgID 发送失败时释放客户端消息ID，允许客户端重试
+func (s *MessageService) ReleaseClientMsgID(scope string, senderID int64, clientMsgID string) {
+	s.redisClient.Del(context.Background(), clientMsgKey(scope, senderID, clientMsgID))
+}
+
+// CheckPrivateSendPermission 检查发送者是否可以给接收者发私信（双方账号、拉黑关系、接收者的私信设置）
+func (s *MessageService) CheckPrivateSendPermission(senderID, receiverID int64) error {
+	var sender, receiver models.User
+	if err := s.db.First(&sender, senderID).Error; err != nil {
+		return errors.New("发送者不存在")
+	}
+	if sender.Status != "ACTIVE" {
+		return errors.New("账号已被禁用")
+	}
+	if err := s.db.First(&receiver, receiverID).Error; err != nil {
+		return errors.New("接收者不存在")
+	}
+
+	return s.checkPrivateMessagePermission(senderID, receiverID)
+}
+
+// checkPrivateMessagePermission 检查发送者是否可以给接收者发私信
+func (s *MessageService) checkPrivateMessagePermission(senderID, receiverID int64) error {
+	if isUserBlocked(s.db, receiverID, senderID) {
+		return errors.New("对方已拒绝接收你的消息")
+	}
+
+	if isUserBlocked(s.db, senderID, receiverID) {
+		return errors.New("你已拉黑对方，请先解除拉黑")
+	}
+
+	setting, err := getChatSetting(s.db, receiverID)
+	if err != nil {
+		return err
+	}
+
+	if setting.DMPolicy == "SHARED_ROOM" && !shareChatRoom(s.db, senderID, receiverID) {
+		return errors.New("对方仅接收同一聊天室成员的私信")
+	}
+
+	return nil
+}
+
+// upsertDirectConversation 更新或创建一对一会话记录及双方的参与者记录，返回会话ID
+// 并发发送首条消息时由唯一索引保证同一对用户只有一个会话
+func upsertDirectConversation(tx *gorm.DB, user1ID, user2ID, messageID int64, messageTime time.Time) (int64, error) {
 	// 确保user1ID < user2ID，保持会话记录的一致性
 	if user1ID > user2ID {
 		user1ID, user2ID = user2ID, user1ID
 	}
 
-	// 查找现有会话
-	var conversation models.Conversation
-	err := s.db.Where("user1_id = ? AND user2_id = ?", user1ID, user2ID).First(&conversation).Error
-
-	if err != nil {
-		// 创建新会话
-		conversation = models.Conversation{
-			User1ID:         user1ID,
-			User2ID:         user2ID,
-			LastMessageID:   &messageID,
-			LastMessageTime: &messageTime,
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
-		}
-		if err := s.db.Create(&conversation).Error; err != nil {
-			return 0
-		}
-	} else {
-		// 更新现有会话
-		s.db.Model(&conversation).Updates(map[string]interface{}{
+	conversation := models.Conversation{
+		Type:            "DIRECT",
+		User1ID:         &user1ID,
+		User2ID:         &user2ID,
+		LastMessageID:   &messageID,
+		LastMessageTime: &messageTime,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user1_id"}, {Name: "user2_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
 			"last_message_id":   messageID,
 			"last_message_time": messageTime,
 			"updated_at":        time.Now(),
-		})
+		}),
+	}).Create(&conversation).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// 会话已存在时插入语句不会返回ID，重新查询
+	if err := tx.Where("user1_id = ? AND user2_id = ?", user1ID, user2ID).First(&conversation).Error; err != nil {
+		return 0, err
+	}
+
+	err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create([]models.ConversationParticipant{
+		{ConversationID: conversation.ID, UserID: user1ID, Role: "MEMBER", JoinedAt: time.Now()},
+		{ConversationID: conversation.ID, UserID: user2ID, Role: "MEMBER", JoinedAt: time.Now()},
+	}).Error
+	if err != nil {
+		return 0, err
 	}
 
-	return conversation.ID
+	return conversation.ID, nil
 }
 
 // GetPrivateMessages 获取私聊消息列表
@@ -207,6 +448,11 @@ func (s *MessageService) GetPrivateMessages(user1ID, user2ID int64, page, pageSi
 		user1ID, user2ID, user2ID, user1ID,
 	)
 
+	// 不显示user1删除会话之前的消息
+	if clearedAt := s.conversationClearedAt(user1ID, user2ID); clearedAt != nil {
+		query = query.Where("created_at > ?", *clearedAt)
+	}
+
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -222,36 +468,214 @@ func (s *MessageService) GetPrivateMessages(user1ID, user2ID int64, page, pageSi
 	return messages, total, err
 }
 
-// ConversationWithUnreadCount 会话信息包含未读计数
-type ConversationWithUnreadCount struct {
-	models.Conversation
-	UnreadCount int64 `json:"unreadCount"`
+// ConversationPeer 一对一会话的对方用户信息
+type ConversationPeer struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatarUrl"`
+	Online    bool   `json:"online"`
 }
 
-// GetConversations 获取用户的所有会话列表（包含未读计数）
-func (s *MessageService) GetConversations(userID int64) ([]ConversationWithUnreadCount, error) {
-	var conversations []models.Conversation
+// ConversationListItem 会话列表项（包含未读计数、最后一条消息预览和对方用户信息）
+type ConversationListItem struct {
+	ID                  int64             `json:"id"`
+	Type                string            `json:"type"`
+	Name                string            `json:"name"`
+	ParticipantCount    int64             `json:"participantCount"`
+	UnreadCount         int64             `json:"unreadCount"`
+	IsMuted             bool              `json:"isMuted"`
+	IsPinned            bool              `json:"isPinned"`
+	IsArchived          bool              `json:"isArchived"`
+	LastMessageID       *int64            `json:"lastMessageId"`
+	LastMessageSenderID *int64            `json:"lastMessageSenderId"`
+	LastMessagePreview  string            `json:"lastMessagePreview"`
+	LastMessageTime     *time.Time        `json:"lastMessageTime"`
+	Peer                *ConversationPeer `json:"peer,omitempty"`
+}
+
+// conversationListRow 会话列表查询的结果行
+type conversationListRow struct {
+	ID                  int64
+	Type                string
+	Name                string
+	ParticipantCount    int64
+	UnreadCount         int64
+	IsMuted             bool
+	IsPinned            bool
+	IsArchived          bool
+	LastMessageID       *int64
+	LastMessageSenderID *int64
+	LastMessagePreview  string
+	LastMessageTime     *time.Time
+	PeerID              *int64
+	PeerUsername        string
+	PeerAvatarURL       string
+	SortPinnedAt        string
+	SortTime            string
+}
+
+// conversationCursor 会话列表的分页游标，对应排序字段的取值
+type conversationCursor struct {
+	Pinned   bool   `json:"p"`
+	PinnedAt string `json:"pa"`
+	Time     string `json:"t"`
+	ID       int64  `json:"id"`
+}
 
-	err := s.db.Where("user1_id = ? OR user2_id = ?", userID, userID).
-		Preload("LastMessage").
-		Order("last_message_time DESC").
-		Find(&conversations).Error
+// conversationPreviewLength 最后一条消息预览的字符数
+const conversationPreviewLength = 50
+
+// GetConversations 分页获取用户的会话列表，置顶会话在前，archived为true时返回已归档的会话
+// cursor为上一页返回的游标，返回值中的游标为空表示没有更多数据
+func (s *MessageService) GetConversations(userID int64, archived bool, cursor string, limit int) ([]ConversationListItem, string, error) {
+	query := s.db.Table("conversation AS c").
+		Select(`c.id, c.type, c.name,
+			(SELECT COUNT(*) FROM conversation_participant WHERE conversation_id = c.id) AS participant_count,
+			COALESCE(u.unread_count, 0) AS unread_count,
+			cp.is_muted, cp.is_pinned, cp.is_archived,
+			pm.id AS last_message_id, pm.sender_id AS last_message_sender_id,
+			LEFT(pm.content, ?) AS last_message_preview, pm.created_at AS last_message_time,
+			peer.id AS peer_id, peer.username AS peer_username, peer.avatar_url AS peer_avatar_url,
+			DATE_FORMAT(COALESCE(cp.pinned_at, '1970-01-01'), '%Y-%m-%d %H:%i:%s.%f') AS sort_pinned_at,
+			DATE_FORMAT(COALESCE(c.last_message_time, c.created_at), '%Y-%m-%d %H:%i:%s.%f') AS sort_time`, conversationPreviewLength).
+		Joins("JOIN conversation_participant AS cp ON cp.conversation_id = c.id AND cp.user_id = ?", userID).
+		Joins("LEFT JOIN conversation_unread_count AS u ON u.conversation_id = c.id AND u.user_id = cp.user_id").
+		Joins("LEFT JOIN private_message AS pm ON pm.id = c.last_message_id AND pm.deleted_at IS NULL AND (cp.cleared_at IS NULL OR pm.created_at > cp.cleared_at)").
+		Joins("LEFT JOIN user AS peer ON c.type = 'DIRECT' AND peer.id = IF(c.user1_id = ?, c.user2_id, c.user1_id)", userID).
+		Where("cp.is_archived = ?", archived).
+		// 不显示已删除（删除后无新消息）的会话和与已拉黑用户的一对一会话
+		Where("cp.cleared_at IS NULL OR c.last_message_time > cp.cleared_at").
+		Where("c.type = 'GROUP' OR NOT EXISTS (SELECT 1 FROM user_block WHERE user_block.blocker_id = ? AND user_block.blocked_id = peer.id)", userID)
+
+	if cursor != "" {
+		var after conversationCursor
+		data, err := base64.URLEncoding.DecodeString(cursor)
+		if err != nil || json.Unmarshal(data, &after) != nil {
+			return nil, "", errors.New("无效的分页游标")
+		}
+		query = query.Where("(cp.is_pinned, COALESCE(cp.pinned_at, '1970-01-01'), COALESCE(c.last_message_time, c.created_at), c.id) < (?, ?, ?, ?)",
+			after.Pinned, after.PinnedAt, after.Time, after.ID)
+	}
 
+	// 多取一条用于判断是否还有下一页
+	var rows []conversationListRow
+	err := query.Order("cp.is_pinned DESC, COALESCE(cp.pinned_at, '1970-01-01') DESC, COALESCE(c.last_message_time, c.created_at) DESC, c.id DESC").
+		Limit(limit + 1).
+		Scan(&rows).Error
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	// 为每个会话添加未读计数
-	var result []ConversationWithUnreadCount
-	for _, conv := range conversations {
-		unreadCount, _ := s.GetConversationUnreadCount(conv.ID, userID)
-		result = append(result, ConversationWithUnreadCount{
-			Conversation: conv,
-			UnreadCount:  unreadCount,
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		data, _ := json.Marshal(conversationCursor{
+			Pinned:   last.IsPinned,
+			PinnedAt: last.SortPinnedAt,
+			Time:     last.SortTime,
+			ID:       last.ID,
 		})
+		nextCursor = base64.URLEncoding.EncodeToString(data)
+	}
+
+	// 批量查询对方用户的在线状态
+	var peerIDs []int64
+	for _, row := range rows {
+		if row.PeerID != nil {
+			peerIDs = append(peerIDs, *row.PeerID)
+		}
+	}
+	online := campusredis.GetUsersOnline(peerIDs)
+
+	items := make([]ConversationListItem, 0, len(rows))
+	for _, row := range rows {
+		item := ConversationListItem{
+			ID:                  row.ID,
+			Type:                row.Type,
+			Name:                row.Name,
+			ParticipantCount:    row.ParticipantCount,
+			UnreadCount:         row.UnreadCount,
+			IsMuted:             row.IsMuted,
+			IsPinned:            row.IsPinned,
+			IsArchived:          row.IsArchived,
+			LastMessageID:       row.LastMessageID,
+			LastMessageSenderID: row.LastMessageSenderID,
+			LastMessagePreview:  row.LastMessagePreview,
+			LastMessageTime:     row.LastMessageTime,
+		}
+		if row.PeerID != nil {
+			item.Peer = &ConversationPeer{
+				ID:        *row.PeerID,
+				Username:  row.PeerUsername,
+				AvatarURL: row.PeerAvatarURL,
+				Online:    online[*row.PeerID],
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nextCursor, nil
+}
+
+// UpdateConversationSettings 更新用户对会话的免打扰、置顶、归档设置
+func (s *MessageService) UpdateConversationSettings(conversationID, userID int64, updates map[string]interface{}) error {
+	var participant models.ConversationParticipant
+	if err := s.db.Where("conversation_id = ? AND user_id = ?", conversationID, userID).First(&participant).Error; err != nil {
+		return errors.New("用户不在该会话中")
+	}
+
+	if pinned, ok := updates["is_pinned"]; ok {
+		if pinned.(bool) {
+			updates["pinned_at"] = time.Now()
+		} else {
+			updates["pinned_at"] = nil
+		}
+	}
+
+	return s.db.Model(&participant).Updates(updates).Error
+}
+
+// DeleteConversationForMe 删除会话（仅对当前用户生效），此前的消息不再显示，对方不受影响
+func (s *MessageService) DeleteConversationForMe(conversationID, userID int64) error {
+	var participant models.ConversationParticipant
+	if err := s.db.Where("conversation_id = ? AND user_id = ?", conversationID, userID).First(&participant).Error; err != nil {
+		return errors.New("用户不在该会话中")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&participant).Updates(map[string]interface{}{
+			"cleared_at":  time.Now(),
+			"is_pinned":   false,
+			"pinned_at":   nil,
+			"is_archived": false,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.ConversationUnreadCount{}).
+			Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+			Update("unread_count", 0).Error
+	})
+}
+
+// conversationClearedAt 获取用户删除一对一会话的时间，未删除时返回nil
+func (s *MessageService) conversationClearedAt(userID, otherUserID int64) *time.Time {
+	user1ID, user2ID := userID, otherUserID
+	if user1ID > user2ID {
+		user1ID, user2ID = user2ID, user1ID
+	}
+
+	var participant models.ConversationParticipant
+	err := s.db.Joins("JOIN conversation ON conversation.id = conversation_participant.conversation_id").
+		Where("conversation.type = ? AND conversation.user1_id = ? AND conversation.user2_id = ?", "DIRECT", user1ID, user2ID).
+		Where("conversation_participant.user_id = ?", userID).
+		First(&participant).Error
+	if err != nil {
+		return nil
 	}
 
-	return result, nil
+	return participant.ClearedAt
 }
 
 // GetConversationUnreadCount 获取指定会话的未读消息数量
@@ -267,12 +691,13 @@ func (s *MessageService) GetConversationUnreadCount(conversationID, userID int64
 	return unreadCount.UnreadCount, nil
 }
 
-// GetUserTotalUnreadCount 获取用户所有会话的未读消息总数
+// GetUserTotalUnreadCount 获取用户所有会话的未读消息总数（不含免打扰和已归档的会话）
 func (s *MessageService) GetUserTotalUnreadCount(userID int64) (int64, error) {
 	var totalCount int64
 	err := s.db.Model(&models.ConversationUnreadCount{}).
-		Where("user_id = ?", userID).
-		Select("COALESCE(SUM(unread_count), 0)").
+		Joins("JOIN conversation_participant AS cp ON cp.conversation_id = conversation_unread_count.conversation_id AND cp.user_id = conversation_unread_count.user_id").
+		Where("conversation_unread_count.user_id = ? AND cp.is_muted = ? AND cp.is_archived = ?", userID, false, false).
+		Select("COALESCE(SUM(conversation_unread_count.unread_count), 0)").
 		Scan(&totalCount).Error
 	return totalCount, err
 }
@@ -284,30 +709,21 @@ func (s *MessageService) ClearConversationUnreadCount(conversationID, userID int
 		Update("unread_count", 0).Error
 }
 
-// incrementConversationUnreadCount 增加指定会话的未读消息计数
-func (s *MessageService) incrementConversationUnreadCount(conversationID, userID int64) error {
-	// 尝试更新现有记录
-	result := s.db.Model(&models.ConversationUnreadCount{}).
-		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
-		Update("unread_count", gorm.Expr("unread_count + ?", 1))
-
-	if result.Error != nil {
-		return result.Error
-	}
-
-	// 如果没有找到记录，创建新记录
-	if result.RowsAffected == 0 {
-		unreadCount := &models.ConversationUnreadCount{
-			ConversationID: conversationID,
-			UserID:         userID,
-			UnreadCount:    1,
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
-		}
-		return s.db.Create(unreadCount).Error
-	}
-
-	return nil
+// incrementConversationUnreadCount 增加指定会话的未读消息计数，无记录时创建
+func incrementConversationUnreadCount(tx *gorm.DB, conversationID, userID int64) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "conversation_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"unread_count": gorm.Expr("unread_count + ?", 1),
+			"updated_at":   time.Now(),
+		}),
+	}).Create(&models.ConversationUnreadCount{
+		ConversationID: conversationID,
+		UserID:         userID,
+		UnreadCount:    1,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}).Error
 }
 
 // SearchPrivateMessages 搜索私聊消息
@@ -320,6 +736,11 @@ func (s *MessageService) SearchPrivateMessages(user1ID, user2ID int64, keyword s
 		user1ID, user2ID, user2ID, user1ID, "%"+keyword+"%",
 	)
 
+	// 不搜索user1删除会话之前的消息
+	if clearedAt := s.conversationClearedAt(user1ID, user2ID); clearedAt != nil {
+		query = query.Where("created_at > ?", *clearedAt)
+	}
+
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -359,3 +780,15 @@ func (s *MessageService) CleanupOldPrivateMessages(daysToKeep int) error {
 	cutoffDate := time.Now().AddDate(0, 0, -daysToKeep)
 	return s.db.Where("created_at < ?", cutoffDate).Delete(&models.PrivateMessage{}).Error
 }
+
+// checkUserActive 检查用户存在且账号未被禁用
+func checkUserActive(db *gorm.DB, userID int64) error {
+	var user models.User
+	if err := db.Select("id, status").First(&user, userID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if user.Status != "ACTIVE" {
+		return errors.New("账号已被禁用")
+	}
+	return nil
+}
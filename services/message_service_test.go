@@ -0,0 +1,119 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 连接TEST_DB_DSN指定的MySQL测试库并迁移表结构，未设置时跳过测试
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("未设置TEST_DB_DSN，跳过需要MySQL的测试")
+	}
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("连接测试数据库失败: %v", err)
+	}
+
+	database.DB = db
+	if err := database.AutoMigrate(); err != nil {
+		t.Fatalf("迁移表结构失败: %v", err)
+	}
+
+	return db
+}
+
+// createTestUser 创建测试用户，测试结束后删除
+func createTestUser(t *testing.T, db *gorm.DB, name string) *models.User {
+	t.Helper()
+
+	suffix := fmt.Sprintf("%s_%d", name, time.Now().UnixNano())
+	user := &models.User{
+		Username:    suffix,
+		Password:    "x",
+		Email:       suffix + "@test.local",
+		CreatedTime: time.Now(),
+		Status:      "ACTIVE",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("创建测试用户失败: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Delete(&models.User{}, user.ID)
+	})
+
+	return user
+}
+
+func TestSendPrivateMessageConcurrentFirstMessages(t *testing.T) {
+	db := openTestDB(t)
+	sender := createTestUser(t, db, "sender")
+	receiver := createTestUser(t, db, "receiver")
+
+	user1ID, user2ID := sender.ID, receiver.ID
+	if user1ID > user2ID {
+		user1ID, user2ID = user2ID, user1ID
+	}
+	t.Cleanup(func() {
+		var conversationIDs []int64
+		db.Model(&models.Conversation{}).Where("user1_id = ? AND user2_id = ?", user1ID, user2ID).Pluck("id", &conversationIDs)
+		if len(conversationIDs) > 0 {
+			db.Where("conversation_id IN ?", conversationIDs).Delete(&models.ConversationUnreadCount{})
+			db.Where("conversation_id IN ?", conversationIDs).Delete(&models.ConversationParticipant{})
+			db.Delete(&models.Conversation{}, conversationIDs)
+		}
+		db.Where("sender_id = ?", sender.ID).Delete(&models.PrivateMessage{})
+	})
+
+	service := NewMessageService()
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, _, err := service.SendPrivateMessage(sender.ID, receiver.ID, fmt.Sprintf("消息%d", i), ""); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("发送私聊消息失败: %v", err)
+	}
+
+	var conversations []models.Conversation
+	if err := db.Where("user1_id = ? AND user2_id = ?", user1ID, user2ID).Find(&conversations).Error; err != nil {
+		t.Fatalf("查询会话失败: %v", err)
+	}
+	if len(conversations) != 1 {
+		t.Fatalf("会话数 = %d，期望 1", len(conversations))
+	}
+
+	var unread models.ConversationUnreadCount
+	if err := db.Where("conversation_id = ? AND user_id = ?", conversations[0].ID, receiver.ID).First(&unread).Error; err != nil {
+		t.Fatalf("查询未读计数失败: %v", err)
+	}
+	if unread.UnreadCount != n {
+		t.Errorf("未读计数 = %d，期望 %d", unread.UnreadCount, n)
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"campus-canvas-chat/config"
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	campusredis "campus-canvas-chat/redis"
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// RateLimitError 触发限流时返回的错误，携带建议的重试等待时间
+type RateLimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Message
+}
+
+// sendRateScript 先检查所有滑动窗口和慢速模式，全部通过后才记录本次请求，被拒绝的请求不占用任何额度
+// KEYS为各滑动窗口的键，开启慢速模式时最后一个键为慢速模式键
+// ARGV: 当前毫秒时间、窗口毫秒数、本次请求的成员、慢速模式毫秒数（0表示不检查）、各窗口的次数上限
+// 返回 {0, 0} 表示放行，否则返回 {被拒绝的键下标（从1开始）, 需等待的毫秒数}
+var sendRateScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local member = ARGV[3]
+local slowMillis = tonumber(ARGV[4])
+local windowCount = #KEYS
+if slowMillis > 0 then
+	windowCount = windowCount - 1
+end
+
+for i = 1, windowCount do
+	redis.call('ZREMRANGEBYSCORE', KEYS[i], 0, now - window)
+	if redis.call('ZCARD', KEYS[i]) >= tonumber(ARGV[4 + i]) then
+		local oldest = redis.call('ZRANGE', KEYS[i], 0, 0, 'WITHSCORES')
+		return {i, tonumber(oldest[2]) + window - now}
+	end
+end
+
+if slowMillis > 0 then
+	local slowKey = KEYS[#KEYS]
+	if not redis.call('SET', slowKey, 1, 'NX', 'PX', slowMillis) then
+		return {#KEYS, redis.call('PTTL', slowKey)}
+	end
+end
+
+for i = 1, windowCount do
+	redis.call('ZADD', KEYS[i], now, member)
+	redis.call('PEXPIRE', KEYS[i], window)
+end
+return {0, 0}
+`)
+
+// rateLimitRule 一个滑动窗口限流规则
+type rateLimitRule struct {
+	key     string
+	limit   int
+	message string
+}
+
+// rateLimitSequence 保证同一毫秒内的请求在有序集合中成员唯一
+var rateLimitSequence int64
+
+type RateLimitService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	config      config.RateLimitConfig
+}
+
+func NewRateLimitService() *RateLimitService {
+	return &RateLimitService{
+		db:          database.GetDB(),
+		redisClient: campusredis.GetClient(),
+		config:      config.GetConfig().RateLimit,
+	}
+}
+
+// CheckSendRate 检查发送消息是否超出IP、用户、聊天室的频率限制以及聊天室的慢速模式，chatRoomID为空表示私聊
+// 所有限制在一个Lua脚本中原子地检查和记录；通过时已占用慢速模式间隔，发送失败需调用ReleaseSlowMode释放
+func (s *RateLimitService) CheckSendRate(userID int64, chatRoomID *int64, ip string) error {
+	rules := make([]rateLimitRule, 0, 3)
+	if ip != "" {
+		rules = append(rules, rateLimitRule{fmt.Sprintf("ratelimit:ip:%s", ip), s.config.IPLimit, "发送过于频繁，请稍后再试"})
+	}
+	rules = append(rules, rateLimitRule{fmt.Sprintf("ratelimit:user:%d", userID), s.config.UserLimit, "发送过于频繁，请稍后再试"})
+
+	slowModeSeconds := 0
+	if chatRoomID != nil {
+		rules = append(rules, rateLimitRule{fmt.Sprintf("ratelimit:room:%d", *chatRoomID), s.config.RoomLimit, "聊天室消息过多，请稍后再试"})
+		slowModeSeconds = s.slowModeSeconds(*chatRoomID, userID)
+	}
+
+	// 未配置的限制不参与检查
+	active := make([]rateLimitRule, 0, len(rules))
+	if s.config.Window > 0 {
+		for _, rule := range rules {
+			if rule.limit > 0 {
+				active = append(active, rule)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(active)+1)
+	now := time.Now().UnixMilli()
+	args := []interface{}{
+		now,
+		s.config.Window.Milliseconds(),
+		fmt.Sprintf("%d-%d", now, atomic.AddInt64(&rateLimitSequence, 1)),
+		(time.Duration(slowModeSeconds) * time.Second).Milliseconds(),
+	}
+	for _, rule := range active {
+		keys = append(keys, rule.key)
+		args = append(args, rule.limit)
+	}
+	if slowModeSeconds > 0 {
+		keys = append(keys, slowModeKey(*chatRoomID, userID))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	result, err := sendRateScript.Run(context.Background(), s.redisClient, keys, args...).Int64Slice()
+	if err != nil || len(result) != 2 {
+		// Redis不可用时放行
+		log.Printf("限流检查失败: %v", err)
+		return nil
+	}
+
+	rejected, waitMillis := int(result[0]), result[1]
+	if rejected == 0 {
+		return nil
+	}
+
+	retryAfter := time.Duration(waitMillis) * time.Millisecond
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+	if rejected <= len(active) {
+		return &RateLimitError{Message: active[rejected-1].message, RetryAfter: retryAfter}
+	}
+	return &RateLimitError{
+		Message:    fmt.Sprintf("聊天室已开启慢速模式，每%d秒只能发言一次", slowModeSeconds),
+		RetryAfter: retryAfter,
+	}
+}
+
+// ReleaseSlowMode 群聊消息发送失败时释放CheckSendRate占用的慢速模式间隔
+func (s *RateLimitService) ReleaseSlowMode(chatRoomID, userID int64) {
+	if err := s.redisClient.Del(context.Background(), slowModeKey(chatRoomID, userID)).Err(); err != nil {
+		log.Printf("释放慢速模式失败: %v", err)
+	}
+}
+
+// slowModeSeconds 成员在聊天室需遵守的慢速模式间隔秒数，未开启慢速模式或房主、管理员返回0
+func (s *RateLimitService) slowModeSeconds(chatRoomID, userID int64) int {
+	var room models.ChatRoom
+	if err := s.db.Select("id, slow_mode_seconds").First(&room, chatRoomID).Error; err != nil || room.SlowModeSeconds <= 0 {
+		return 0
+	}
+
+	// 房主和管理员不受慢速模式限制
+	var member models.ChatRoomMember
+	if err := s.db.Select("role").Where("chat_room_id = ? AND user_id = ?", chatRoomID, userID).First(&member).Error; err == nil && member.Role != "MEMBER" {
+		return 0
+	}
+
+	return room.SlowModeSeconds
+}
+
+// slowModeKey 成员在聊天室的慢速模式键
+func slowModeKey(chatRoomID, userID int64) string {
+	return fmt.Sprintf("slowmode:%d:%d", chatRoomID, userID)
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReportService struct {
+	db              *gorm.DB
+	chatRoomService *ChatRoomService
+	adminService    *AdminService
+}
+
+func NewReportService() *ReportService {
+	return &ReportService{
+		db:              database.GetDB(),
+		chatRoomService: NewChatRoomService(),
+		adminService:    NewAdminService(),
+	}
+}
+
+// ReportResolution 举报处理结果
+type ReportResolution struct {
+	Status   string        // ACTIONED 或 DISMISSED
+	Action   string        // 处理动作，Status为ACTIONED时必填
+	Duration time.Duration // 禁言或封禁时长，0表示永久
+	Note     string
+}
+
+// roomReportTypes 房主和管理员可以处理的聊天室内举报类型
+var roomReportTypes = []string{"GROUP_MESSAGE", "USER"}
+
+// CreateReport 提交举报
+func (s *ReportService) CreateReport(report *models.Report) error {
+	var reporter models.User
+	if err := s.db.First(&reporter, report.ReporterID).Error; err != nil {
+		return errors.New("举报人不存在")
+	}
+	if reporter.Status != "ACTIVE" {
+		return errors.New("账号已被禁用")
+	}
+
+	switch report.TargetType {
+	case "GROUP_MESSAGE":
+		if report.ChatRoomID == nil {
+			return errors.New("举报群聊消息需指定聊天室")
+		}
+		if err := s.checkRoomMember(*report.ChatRoomID, report.ReporterID); err != nil {
+			return err
+		}
+
+		// 发送者和内容快照以数据库中的消息为准，不信任客户端提交的内容；仍在缓存中的消息没有ID，暂不能举报
+		if report.TargetID <= 0 {
+			return errors.New("消息尚未存档，请稍后再举报或直接举报该用户")
+		}
+		var message models.Message
+		if err := s.db.Where("id = ? AND chat_room_id = ?", report.TargetID, *report.ChatRoomID).First(&message).Error; err != nil {
+			return errors.New("消息不存在")
+		}
+		report.TargetUserID = &message.UserID
+		report.ContentSnapshot = message.Content
+
+	case "PRIVATE_MESSAGE":
+		var message models.PrivateMessage
+		if err := s.db.First(&message, report.TargetID).Error; err != nil {
+			return errors.New("消息不存在")
+		}
+		if message.ReceiverID != report.ReporterID && !s.isConversationParticipant(message.ConversationID, report.ReporterID) {
+			return errors.New("只能举报自己收到的私聊消息")
+		}
+		report.TargetUserID = &message.SenderID
+		report.ContentSnapshot = message.Content
+		report.ChatRoomID = nil
+
+	case "USER":
+		var user models.User
+		if err := s.db.First(&user, report.TargetID).Error; err != nil {
+			return errors.New("被举报用户不存在")
+		}
+		report.TargetUserID = &user.ID
+
+		// 在聊天室内举报用户时，房主和管理员也可以处理
+		if report.ChatRoomID != nil {
+			if err := s.checkRoomMember(*report.ChatRoomID, report.ReporterID); err != nil {
+				return err
+			}
+		}
+
+	case "CHATROOM":
+		var room models.ChatRoom
+		if err := s.db.First(&room, report.TargetID).Error; err != nil {
+			return errors.New("聊天室不存在")
+		}
+		report.ChatRoomID = &room.ID
+		report.TargetUserID = &room.CreatorID
+
+	default:
+		return errors.New("无效的举报类型")
+	}
+
+	if report.TargetUserID != nil && *report.TargetUserID == report.ReporterID {
+		return errors.New("不能举报自己")
+	}
+
+	// 同一用户对同一目标只能有一条待处理的举报
+	if report.TargetID > 0 {
+		var count int64
+		s.db.Model(&models.Report{}).
+			Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?",
+				report.ReporterID, report.TargetType, report.TargetID, "OPEN").
+			Count(&count)
+		if count > 0 {
+			return errors.New("已举报过该内容，请等待处理")
+		}
+	}
+
+	report.Status = "OPEN"
+	report.CreatedAt = time.Now()
+	report.UpdatedAt = time.Now()
+
+	return s.db.Create(report).Error
+}
+
+// checkRoomMember 检查用户是否是聊天室成员
+func (s *ReportService) checkRoomMember(roomID, userID int64) error {
+	var member models.ChatRoomMember
+	if err := s.db.Where("chat_room_id = ? AND user_id = ?", roomID, userID).First(&member).Error; err != nil {
+		return errors.New("用户不是该聊天室成员")
+	}
+	return nil
+}
+
+// isConversationParticipant 检查用户是否是会话参与者
+func (s *ReportService) isConversationParticipant(conversationID *int64, userID int64) bool {
+	if conversationID == nil {
+		return false
+	}
+
+	var count int64
+	s.db.Model(&models.ConversationParticipant{}).
+		Where("conversation_id = ? AND user_id = ?", *conversationID, userID).
+		Count(&count)
+	return count > 0
+}
+
+// GetRoomReports 获取聊天室内的举报队列（房主和管理员可查看）
+func (s *ReportService) GetRoomReports(roomID, operatorID int64, status string, page, pageSize int) ([]models.Report, int64, error) {
+	if _, err := s.chatRoomService.checkManager(roomID, operatorID); err != nil {
+		return nil, 0, err
+	}
+
+	query := s.db.Model(&models.Report{}).
+		Where("chat_room_id = ? AND target_type IN ?", roomID, roomReportTypes)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	return s.findReports(query, page, pageSize)
+}
+
+// GetReports 获取全平台举报队列（平台管理员可查看）
+func (s *ReportService) GetReports(status, targetType, category string, page, pageSize int) ([]models.Report, int64, error) {
+	query := s.db.Model(&models.Report{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	return s.findReports(query, page, pageSize)
+}
+
+// GetUserReports 获取用户提交的举报及处理进度
+func (s *ReportService) GetUserReports(reporterID int64, page, pageSize int) ([]models.Report, int64, error) {
+	query := s.db.Model(&models.Report{}).Where("reporter_id = ?", reporterID)
+	return s.findReports(query, page, pageSize)
+}
+
+// findReports 分页查询举报，先提交的排在前面
+func (s *ReportService) findReports(query *gorm.DB, page, pageSize int) ([]models.Report, int64, error) {
+	var reports []models.Report
+	var total int64
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	offset := (page - 1) * pageSize
+	err := query.Preload("Reporter").
+		Order("created_at ASC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&reports).Error
+
+	return reports, total, err
+}
+
+// HandleRoomReport 房主或管理员处理聊天室内的举报，可删除消息、禁言或封禁
+func (s *ReportService) HandleRoomReport(roomID, reportID, operatorID int64, resolution ReportResolution) (*models.Report, error) {
+	operatorMember, err := s.chatRoomService.checkManager(roomID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.getOpenReport(reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.ChatRoomID == nil || *report.ChatRoomID != roomID ||
+		(report.TargetType != "GROUP_MESSAGE" && report.TargetType != "USER") {
+		return nil, errors.New("该举报不属于本聊天室")
+	}
+
+	if resolution.Status == "ACTIONED" {
+		switch resolution.Action {
+		case "DELETE_MESSAGE", "MUTE", "BAN":
+		default:
+			return nil, errors.New("房主和管理员只能删除消息、禁言或封禁")
+		}
+	}
+
+	return s.resolveReport(report, operatorID, resolution, func(tx *gorm.DB) error {
+		return s.applyAction(tx, report, operatorID, operatorMember.Role, nil, resolution)
+	})
+}
+
+// HandleReport 平台管理员处理举报，可删除消息、禁言、封禁、禁用用户或停用聊天室
+func (s *ReportService) HandleReport(admin *models.Admin, reportID int64, resolution ReportResolution) (*models.Report, error) {
+	report, err := s.getOpenReport(reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resolveReport(report, admin.UserID, resolution, func(tx *gorm.DB) error {
+		return s.applyAction(tx, report, admin.UserID, "", admin, resolution)
+	})
+}
+
+// getOpenReport 获取待处理的举报
+func (s *ReportService) getOpenReport(reportID int64) (*models.Report, error) {
+	var report models.Report
+	if err := s.db.First(&report, reportID).Error; err != nil {
+		return nil, errors.New("举报不存在")
+	}
+
+	if report.Status != "OPEN" {
+		return nil, errors.New("举报已处理")
+	}
+
+	return &report, nil
+}
+
+// applyAction 在处理举报的事务中执行对应的处理动作，各动作自行记录审计日志
+// operatorRole为空、admin不为空表示平台管理员处理，不要求其是聊天室房主或管理员
+func (s *ReportService) applyAction(tx *gorm.DB, report *models.Report, operatorID int64, operatorRole string, admin *models.Admin, resolution ReportResolution) error {
+	switch resolution.Action {
+	case "DELETE_MESSAGE":
+		return s.deleteReportedMessage(tx, report, operatorID)
+
+	case "MUTE":
+		if report.ChatRoomID == nil || report.TargetUserID == nil {
+			return errors.New("该举报无法执行禁言")
+		}
+		if admin != nil {
+			_, err := s.chatRoomService.platformMuteMember(tx, *report.ChatRoomID, operatorID, *report.TargetUserID, resolution.Duration, resolution.Note)
+			return err
+		}
+		_, err := s.chatRoomService.muteMember(tx, *report.ChatRoomID, operatorID, operatorRole, *report.TargetUserID, true, resolution.Duration, resolution.Note)
+		return err
+
+	case "BAN":
+		if report.ChatRoomID == nil || report.TargetUserID == nil {
+			return errors.New("该举报无法执行封禁")
+		}
+		if admin != nil {
+			return s.chatRoomService.platformBanMember(tx, *report.ChatRoomID, operatorID, *report.TargetUserID, resolution.Duration, resolution.Note)
+		}
+		return s.chatRoomService.banMember(tx, *report.ChatRoomID, operatorID, operatorRole, *report.TargetUserID, resolution.Duration, resolution.Note)
+
+	case "DEACTIVATE_ROOM":
+		if report.ChatRoomID == nil || admin == nil {
+			return errors.New("该举报无法停用聊天室")
+		}
+		return s.adminService.deactivateChatRoom(tx, operatorID, *report.ChatRoomID, resolution.Note)
+
+	case "DISABLE_USER":
+		if report.TargetUserID == nil || admin == nil {
+			return errors.New("该举报无法禁用用户")
+		}
+		return s.adminService.setUserStatus(tx, admin, *report.TargetUserID, "DISABLED")
+	}
+
+	return errors.New("无效的处理动作")
+}
+
+// deleteReportedMessage 删除被举报的消息
+func (s *ReportService) deleteReportedMessage(tx *gorm.DB, report *models.Report, operatorID int64) error {
+	switch report.TargetType {
+	case "GROUP_MESSAGE":
+		if report.TargetID == 0 {
+			return errors.New("消息未存档，无法删除")
+		}
+		if err := tx.Where("id = ? AND chat_room_id = ?", report.TargetID, *report.ChatRoomID).Delete(&models.Message{}).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "DELETE_MESSAGE", "GROUP_MESSAGE", report.TargetID, report.ChatRoomID,
+			map[string]interface{}{"content": report.ContentSnapshot},
+			nil,
+		)
+
+	case "PRIVATE_MESSAGE":
+		if err := tx.Delete(&models.PrivateMessage{}, report.TargetID).Error; err != nil {
+			return err
+		}
+
+		return writeAuditLog(tx, operatorID, "DELETE_MESSAGE", "PRIVATE_MESSAGE", report.TargetID, nil,
+			map[string]interface{}{"content": report.ContentSnapshot},
+			nil,
+		)
+	}
+
+	return errors.New("该举报不是消息举报")
+}
+
+// resolveReport 先认领仍待处理的举报，再在同一事务中执行处理动作，避免并发处理时重复执行
+func (s *ReportService) resolveReport(report *models.Report, handlerID int64, resolution ReportResolution, apply func(tx *gorm.DB) error) (*models.Report, error) {
+	now := time.Now()
+	action := ""
+	if resolution.Status == "ACTIONED" {
+		action = resolution.Action
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 只更新仍待处理的举报，并发处理时只有一个能认领成功
+		result := tx.Model(&models.Report{}).
+			Where("id = ? AND status = ?", report.ID, "OPEN").
+			Updates(map[string]interface{}{
+				"status":          resolution.Status,
+				"resolution":      action,
+				"resolution_note": resolution.Note,
+				"handler_id":      handlerID,
+				"handled_at":      now,
+				"updated_at":      now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("举报已处理")
+		}
+
+		if resolution.Status == "ACTIONED" {
+			if err := apply(tx); err != nil {
+				return err
+			}
+		}
+
+		return writeAuditLog(tx, handlerID, "HANDLE_REPORT", "REPORT", report.ID, report.ChatRoomID,
+			map[string]interface{}{"status": "OPEN"},
+			map[string]interface{}{"status": resolution.Status, "resolution": action, "note": resolution.Note},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Status = resolution.Status
+	report.Resolution = action
+	report.ResolutionNote = resolution.Note
+	report.HandlerID = &handlerID
+	report.HandledAt = &now
+	report.UpdatedAt = now
+
+	return report, nil
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"campus-canvas-chat/database"
+	"campus-canvas-chat/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserPrivacyService struct {
+	db *gorm.DB
+}
+
+func NewUserPrivacyService() *UserPrivacyService {
+	return &UserPrivacyService{
+		db: database.GetDB(),
+	}
+}
+
+// BlockUser 拉黑用户，同时清零与对方会话的未读计数
+func (s *UserPrivacyService) BlockUser(blockerID, blockedID int64) error {
+	if blockerID == blockedID {
+		return errors.New("不能拉黑自己")
+	}
+
+	var user models.User
+	if err := s.db.First(&user, blockedID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+
+	if isUserBlocked(s.db, blockerID, blockedID) {
+		return errors.New("已拉黑该用户")
+	}
+
+	user1ID, user2ID := blockerID, blockedID
+	if user1ID > user2ID {
+		user1ID, user2ID = user2ID, user1ID
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.UserBlock{
+			BlockerID: blockerID,
+			BlockedID: blockedID,
+			CreatedAt: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		var conversation models.Conversation
+		if err := tx.Where("user1_id = ? AND user2_id = ?", user1ID, user2ID).First(&conversation).Error; err == nil {
+			return tx.Model(&models.ConversationUnreadCount{}).
+				Where("conversation_id = ? AND user_id = ?", conversation.ID, blockerID).
+				Update("unread_count", 0).Error
+		}
+
+		return nil
+	})
+}
+
+// UnblockUser 解除拉黑
+func (s *UserPrivacyService) UnblockUser(blockerID, blockedID int64) error {
+	result := s.db.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Delete(&models.UserBlock{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("未拉黑该用户")
+	}
+	return nil
+}
+
+// GetBlockedUsers 获取用户的拉黑列表
+func (s *UserPrivacyService) GetBlockedUsers(userID int64) ([]models.UserBlock, error) {
+	var blocks []models.UserBlock
+	err := s.db.Where("blocker_id = ?", userID).
+		Preload("Blocked").
+		Order("created_at DESC").
+		Find(&blocks).Error
+	return blocks, err
+}
+
+// GetChatSetting 获取用户的聊天隐私设置
+func (s *UserPrivacyService) GetChatSetting(userID int64) (*models.UserChatSetting, error) {
+	return getChatSetting(s.db, userID)
+}
+
+// UpdateChatSetting 更新用户的聊天隐私设置
+func (s *UserPrivacyService) UpdateChatSetting(userID int64, updates map[string]interface{}) (*models.UserChatSetting, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	setting, err := getChatSetting(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := updates["dm_policy"]; ok {
+		setting.DMPolicy = value.(string)
+	}
+	if value, ok := updates["hide_blocked_in_groups"]; ok {
+		setting.HideBlockedInGroups = value.(bool)
+	}
+	setting.UpdatedAt = time.Now()
+
+	// 首次修改时创建记录
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"dm_policy", "hide_blocked_in_groups", "updated_at"}),
+	}).Create(setting).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return setting, nil
+}
+
+// GetHiddenGroupSenderIDs 获取用户在群聊中需要隐藏消息的发送者（未开启隐藏时为空）
+func (s *UserPrivacyService) GetHiddenGroupSenderIDs(userID int64) ([]int64, error) {
+	setting, err := getChatSetting(s.db, userID)
+	if err != nil || !setting.HideBlockedInGroups {
+		return nil, err
+	}
+	return blockedUserIDs(s.db, userID)
+}
+
+// getChatSetting 读取聊天隐私设置，无记录时返回默认设置
+func getChatSetting(db *gorm.DB, userID int64) (*models.UserChatSetting, error) {
+	var setting models.UserChatSetting
+	err := db.Where("user_id = ?", userID).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.UserChatSetting{
+			UserID:   userID,
+			DMPolicy: "EVERYONE",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// isUserBlocked 检查blockerID是否拉黑了blockedID
+func isUserBlocked(db *gorm.DB, blockerID, blockedID int64) bool {
+	var count int64
+	db.Model(&models.UserBlock{}).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Count(&count)
+	return count > 0
+}
+
+// blockedUserIDs 获取用户拉黑的所有用户ID
+func blockedUserIDs(db *gorm.DB, userID int64) ([]int64, error) {
+	var userIDs []int64
+	err := db.Model(&models.UserBlock{}).
+		Where("blocker_id = ?", userID).
+		Pluck("blocked_id", &userIDs).Error
+	return userIDs, err
+}
+
+// shareChatRoom 检查两个用户是否同在某个聊天室
+func shareChatRoom(db *gorm.DB, user1ID, user2ID int64) bool {
+	var count int64
+	db.Table("chatroom_member AS a").
+		Joins("JOIN chatroom_member AS b ON a.chat_room_id = b.chat_room_id").
+		Where("a.user_id = ? AND b.user_id = ?", user1ID, user2ID).
+		Count(&count)
+	return count > 0
+}
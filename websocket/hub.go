@@ -4,11 +4,13 @@ import (
 	"campus-canvas-chat/database"
 	"campus-canvas-chat/models"
 	"campus-canvas-chat/redis"
+	"campus-canvas-chat/services"
 	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -25,7 +27,10 @@ type Client struct {
 	Conn   *websocket.Conn
 	UserID int64
 	RoomID *int64 // 可选的房间ID，用于群聊
+	IP     string
 	Send   chan []byte
+
+	hiddenSenders map[int64]bool // 群聊中需要隐藏消息的发送者（已拉黑的用户）
 }
 
 // Hub WebSocket连接管理器
@@ -37,17 +42,24 @@ type Hub struct {
 	Rooms      map[int64]map[*Client]bool // roomID -> clients
 	Users      map[int64]*Client          // userID -> client (用于私聊)
 	Mutex      sync.RWMutex
+
+	messageService  *services.MessageService
+	chatRoomService *services.ChatRoomService
+	contentFilter   *services.ContentFilterService
+	rateLimiter     *services.RateLimitService
+	privacyService  *services.UserPrivacyService
 }
 
 // Message WebSocket消息结构
 type WSMessage struct {
-	Type      string      `json:"type"` // message, join, leave, error
-	RoomID    int64       `json:"room_id"`
-	UserID    int64       `json:"user_id"`
-	Username  string      `json:"username"`
-	Content   string      `json:"content"`
-	Timestamp int64       `json:"timestamp"`
-	Data      interface{} `json:"data,omitempty"`
+	Type        string      `json:"type"` // message, join, leave, error
+	RoomID      int64       `json:"room_id"`
+	UserID      int64       `json:"user_id"`
+	Username    string      `json:"username"`
+	Content     string      `json:"content"`
+	Timestamp   int64       `json:"timestamp"`
+	ClientMsgID string      `json:"client_msg_id,omitempty"` // 客户端生成的消息ID，重试时不重复广播
+	Data        interface{} `json:"data,omitempty"`
 }
 
 // NewHub 创建新的Hub
@@ -59,6 +71,12 @@ func NewHub() *Hub {
 		Unregister: make(chan *Client),
 		Rooms:      make(map[int64]map[*Client]bool),
 		Users:      make(map[int64]*Client),
+
+		messageService:  services.NewMessageService(),
+		chatRoomService: services.NewChatRoomService(),
+		contentFilter:   services.NewContentFilterService(),
+		rateLimiter:     services.NewRateLimitService(),
+		privacyService:  services.NewUserPrivacyService(),
 	}
 }
 
@@ -68,6 +86,8 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.Register:
 			h.registerClient(client)
+			// 离线消息需访问Redis，放到单独的协程中投递，避免阻塞Hub的事件循环
+			go h.deliverOfflineMessages(client)
 
 		case client := <-h.Unregister:
 			h.unregisterClient(client)
@@ -166,6 +186,9 @@ func (h *Hub) broadcastMessage(message []byte) {
 	// 向指定房间的所有客户端发送消息
 	if room, exists := h.Rooms[wsMsg.RoomID]; exists {
 		for client := range room {
+			if client.hiddenSenders[wsMsg.UserID] {
+				continue
+			}
 			select {
 			case client.Send <- message:
 			default:
@@ -195,6 +218,56 @@ func (h *Hub) BroadcastToRoom(roomID int64, message []byte) {
 	}
 }
 
+// BroadcastGroupMessage 向指定房间广播群聊消息，跳过已拉黑发送者并开启隐藏的用户
+func (h *Hub) BroadcastGroupMessage(roomID, senderID int64, message []byte) {
+	h.Mutex.RLock()
+	defer h.Mutex.RUnlock()
+
+	if room, exists := h.Rooms[roomID]; exists {
+		for client := range room {
+			if client.hiddenSenders[senderID] {
+				continue
+			}
+			select {
+			case client.Send <- message:
+			default:
+				close(client.Send)
+				delete(h.Clients, client)
+				delete(room, client)
+			}
+		}
+	}
+}
+
+// RefreshHiddenSenders 重新加载用户在群聊中需要隐藏的发送者（拉黑或隐私设置变更后调用）
+func (h *Hub) RefreshHiddenSenders(userID int64) {
+	hiddenSenders := h.loadHiddenSenders(userID)
+
+	h.Mutex.Lock()
+	defer h.Mutex.Unlock()
+
+	for client := range h.Clients {
+		if client.UserID == userID {
+			client.hiddenSenders = hiddenSenders
+		}
+	}
+}
+
+// loadHiddenSenders 读取用户在群聊中需要隐藏的发送者
+func (h *Hub) loadHiddenSenders(userID int64) map[int64]bool {
+	userIDs, err := h.privacyService.GetHiddenGroupSenderIDs(userID)
+	if err != nil {
+		log.Printf("加载用户 %d 的拉黑列表失败: %v", userID, err)
+		return nil
+	}
+
+	hiddenSenders := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		hiddenSenders[id] = true
+	}
+	return hiddenSenders
+}
+
 // SendToUser 向指定用户发送消息
 func (h *Hub) SendToUser(userID int64, message []byte) {
 	h.Mutex.RLock()
@@ -216,6 +289,136 @@ func (h *Hub) SendToUser(userID int64, message []byte) {
 	}
 }
 
+// DeliverToUser 推送消息给用户，用户未连接到本实例时存为离线消息，下次连接时推送
+func (h *Hub) DeliverToUser(userID int64, message []byte) {
+	h.Mutex.RLock()
+	_, connected := h.Users[userID]
+	h.Mutex.RUnlock()
+
+	if connected {
+		h.SendToUser(userID, message)
+		return
+	}
+
+	if err := redis.CacheMessage(userID, string(message)); err != nil {
+		log.Printf("缓存离线消息失败: %v", err)
+	}
+}
+
+// deliverOfflineMessages 推送用户离线期间缓存的消息（按时间顺序）
+func (h *Hub) deliverOfflineMessages(client *Client) {
+	messages, err := redis.GetOfflineMessages(client.UserID)
+	if err != nil {
+		return
+	}
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		h.SendToClient(client, []byte(messages[i]))
+	}
+}
+
+// SendToClient 向指定连接发送消息（连接已注销时忽略）
+func (h *Hub) SendToClient(client *Client, message []byte) {
+	h.Mutex.RLock()
+	defer h.Mutex.RUnlock()
+
+	if _, ok := h.Clients[client]; !ok {
+		return
+	}
+
+	select {
+	case client.Send <- message:
+	default:
+	}
+}
+
+// DisconnectUser 强制断开用户在本实例上的所有连接（账号被禁用时使用）
+func (h *Hub) DisconnectUser(userID int64, reason string) {
+	h.Mutex.Lock()
+	defer h.Mutex.Unlock()
+
+	noticeData, _ := json.Marshal(WSMessage{
+		Type:      "account_disabled",
+		UserID:    userID,
+		Content:   reason,
+		Timestamp: time.Now().Unix(),
+	})
+
+	disconnected := false
+	for client := range h.Clients {
+		if client.UserID != userID {
+			continue
+		}
+
+		// 先推送通知，关闭发送通道后writePump会发送关闭帧并断开连接
+		select {
+		case client.Send <- noticeData:
+		default:
+		}
+		close(client.Send)
+		delete(h.Clients, client)
+		disconnected = true
+
+		if client.RoomID != nil {
+			if room, exists := h.Rooms[*client.RoomID]; exists {
+				delete(room, client)
+				if len(room) == 0 {
+					delete(h.Rooms, *client.RoomID)
+				}
+			}
+			redis.RemoveUserFromRoom(*client.RoomID, userID)
+		}
+	}
+
+	if disconnected {
+		delete(h.Users, userID)
+		redis.SetUserOffline(userID)
+	}
+}
+
+// DisconnectUserFromRoom 强制断开用户在指定房间的所有连接（踢出或封禁时使用）
+func (h *Hub) DisconnectUserFromRoom(roomID, userID int64, reason string) {
+	h.Mutex.Lock()
+	defer h.Mutex.Unlock()
+
+	room, exists := h.Rooms[roomID]
+	if !exists {
+		return
+	}
+
+	noticeData, _ := json.Marshal(WSMessage{
+		Type:      "kicked",
+		RoomID:    roomID,
+		UserID:    userID,
+		Content:   reason,
+		Timestamp: time.Now().Unix(),
+	})
+
+	for client := range room {
+		if client.UserID != userID {
+			continue
+		}
+
+		// 先推送通知，关闭发送通道后writePump会发送关闭帧并断开连接
+		select {
+		case client.Send <- noticeData:
+		default:
+		}
+		close(client.Send)
+
+		delete(h.Clients, client)
+		delete(room, client)
+		if h.Users[userID] == client {
+			delete(h.Users, userID)
+		}
+	}
+
+	if len(room) == 0 {
+		delete(h.Rooms, roomID)
+	}
+	redis.RemoveUserFromRoom(roomID, userID)
+}
+
 // HandleWebSocket 处理WebSocket连接
 func (h *Hub) HandleWebSocket(c *gin.Context) {
 	// 获取用户ID（必需）
@@ -239,6 +442,11 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	if user.Status != "ACTIVE" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "账号已被禁用"})
+		return
+	}
+
 	// 获取房间ID（可选，用于群聊）
 	roomIDStr := c.Query("room_id")
 	var roomID *int64
@@ -256,6 +464,12 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 			return
 		}
 
+		// 验证用户是否被封禁
+		if h.chatRoomService.IsUserBanned(parsedRoomID, userID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "您已被该聊天室封禁"})
+			return
+		}
+
 		// 验证用户是否是房间成员
 		var member models.ChatRoomMember
 		if err := db.Where("chat_room_id = ? AND user_id = ?", parsedRoomID, userID).First(&member).Error; err != nil {
@@ -278,7 +492,10 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 		Conn:   conn,
 		UserID: userID,
 		RoomID: roomID,
+		IP:     c.ClientIP(),
 		Send:   make(chan []byte, 256),
+
+		hiddenSenders: h.loadHiddenSenders(userID),
 	}
 
 	// 注册客户端
@@ -318,18 +535,94 @@ func (c *Client) readPump(hub *Hub) {
 			wsMsg.RoomID = *c.RoomID
 		}
 
+		// 群聊消息需检查发言权限（成员身份、禁言）
+		if wsMsg.Type == "message" && c.RoomID != nil {
+			if err := hub.messageService.CheckGroupSendPermission(*c.RoomID, c.UserID); err != nil {
+				c.sendError(hub, err.Error())
+				continue
+			}
+
+			// 敏感词过滤
+			content, err := hub.contentFilter.Apply("GROUP_MESSAGE", c.UserID, c.RoomID, wsMsg.Content)
+			if err != nil {
+				c.sendError(hub, err.Error())
+				continue
+			}
+			wsMsg.Content = content
+		}
+
+		// 客户端重试已发送成功的消息时直接回传原消息，不占用频率限制
+		if wsMsg.Type == "message" && wsMsg.ClientMsgID != "" {
+			if cached := hub.messageService.CachedClientMsgResult("ws", c.UserID, wsMsg.ClientMsgID); cached != "" {
+				hub.SendToClient(c, []byte(cached))
+				continue
+			}
+		}
+
+		// 通过权限检查和敏感词过滤后再检查发送频率，被拒绝的发送不占用频率限制
+		// 只有群聊消息占用聊天室的频率限制和慢速模式间隔
+		var rateRoomID *int64
+		if wsMsg.Type == "message" {
+			rateRoomID = c.RoomID
+		}
+		if err := hub.rateLimiter.CheckSendRate(c.UserID, rateRoomID, c.IP); err != nil {
+			c.sendError(hub, err.Error())
+			continue
+		}
+
+		// 客户端重试的消息不重复广播，仅将原消息回传给发送者
+		if wsMsg.Type == "message" && wsMsg.ClientMsgID != "" {
+			claimed, cached := hub.messageService.ClaimClientMsgID("ws", c.UserID, wsMsg.ClientMsgID)
+			if !claimed {
+				c.releaseSlowMode(hub, rateRoomID)
+				if cached != "" {
+					hub.SendToClient(c, []byte(cached))
+				}
+				continue
+			}
+		}
+
 		// 重新序列化消息
 		messageData, err := json.Marshal(wsMsg)
 		if err != nil {
 			log.Printf("序列化消息失败: %v", err)
+			c.releaseSlowMode(hub, rateRoomID)
+			if wsMsg.Type == "message" && wsMsg.ClientMsgID != "" {
+				hub.messageService.ReleaseClientMsgID("ws", c.UserID, wsMsg.ClientMsgID)
+			}
 			continue
 		}
 
+		if wsMsg.Type == "message" && wsMsg.ClientMsgID != "" {
+			hub.messageService.SaveClientMsgResult("ws", c.UserID, wsMsg.ClientMsgID, messageData)
+		}
+
 		// 广播消息
 		hub.Broadcast <- messageData
 	}
 }
 
+// releaseSlowMode 消息未能发出时释放已占用的慢速模式间隔
+func (c *Client) releaseSlowMode(hub *Hub, roomID *int64) {
+	if roomID != nil {
+		hub.rateLimiter.ReleaseSlowMode(*roomID, c.UserID)
+	}
+}
+
+// sendError 向客户端发送错误消息
+func (c *Client) sendError(hub *Hub, content string) {
+	errorData, err := json.Marshal(WSMessage{
+		Type:      "error",
+		UserID:    c.UserID,
+		Content:   content,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return
+	}
+	hub.SendToClient(c, errorData)
+}
+
 // writePump 发送消息
 func (c *Client) writePump() {
 	defer c.Conn.Close()